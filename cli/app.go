@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Files are the default names of input files, used when the respective flag is given without a value.
+type Files struct {
+	Parameters string // Default: parameters.json
+	Observers  string // Default: observers.json
+	Experiment string // Default: experiment.json
+	Systems    string // Default: systems.json
+	Hooks      string // Default: hooks.json
+}
+
+// defaultFiles are the default input file names, for names not set by the app.
+var defaultFiles = Files{
+	Parameters: "parameters.json",
+	Observers:  "observers.json",
+	Experiment: "experiment.json",
+	Systems:    "systems.json",
+	Hooks:      "hooks.json",
+}
+
+// withDefaults returns the files, with empty names replaced by the given defaults.
+func (f Files) withDefaults(def Files) Files {
+	for _, n := range []struct{ value, def *string }{
+		{&f.Parameters, &def.Parameters},
+		{&f.Observers, &def.Observers},
+		{&f.Experiment, &def.Experiment},
+		{&f.Systems, &def.Systems},
+		{&f.Hooks, &def.Hooks},
+	} {
+		if *n.value == "" {
+			*n.value = *n.def
+		}
+	}
+	return f
+}
+
+// App is a builder for the CLI app.
+//
+// Derived models can use it to ship their own executable, with additional sub-commands and flags,
+// custom default file names and their own registry entries, on top of the standard runner:
+//
+//	cli.New().
+//		WithName("ourbees", "ourbees runs our derived beecs model.").
+//		WithFiles(cli.Files{Parameters: "ourbees.json"}).
+//		AddCommand(ourCommand()).
+//		OnInitialize(func() error {
+//			registry.RegisterSystem[OurSystem]()
+//			return nil
+//		}).
+//		Run()
+type App struct {
+	name        string
+	description string
+	files       Files
+	commands    []*cobra.Command
+	flags       []func(flags *pflag.FlagSet)
+	initialize  []func() error
+}
+
+// New creates a new CLI app builder.
+func New() *App {
+	return &App{}
+}
+
+// WithName sets the name of the executable and the description shown in the help.
+func (a *App) WithName(name, description string) *App {
+	a.name = name
+	a.description = description
+	return a
+}
+
+// WithFiles sets the default names of input files. Empty names keep the defaults.
+func (a *App) WithFiles(files Files) *App {
+	a.files = files
+	return a
+}
+
+// AddCommand adds sub-commands.
+func (a *App) AddCommand(cmds ...*cobra.Command) *App {
+	a.commands = append(a.commands, cmds...)
+	return a
+}
+
+// AddFlags adds flags to the root command, which runs models.
+// Flag values can be used in functions registered with [App.OnInitialize].
+func (a *App) AddFlags(fn func(flags *pflag.FlagSet)) *App {
+	a.flags = append(a.flags, fn)
+	return a
+}
+
+// OnInitialize registers a function that is called after parsing flags, before any command is executed.
+// Use it for registering custom observers, systems, resources and drawers.
+func (a *App) OnInitialize(fn func() error) *App {
+	a.initialize = append(a.initialize, fn)
+	return a
+}
+
+// Command creates the root command of the app.
+//
+// Functions registered with [App.OnInitialize] are called before running the root command or any sub-command.
+func (a *App) Command() *cobra.Command {
+	root := rootCommand(a.files.withDefaults(defaultFiles))
+	if a.name != "" {
+		root.Use = a.name
+	}
+	if a.description != "" {
+		root.Short = a.description
+		root.Long = a.description
+	}
+	for _, fn := range a.flags {
+		fn(root.Flags())
+	}
+	root.AddCommand(a.commands...)
+
+	initialize := a.initialize
+	withInitializers(root, true, func() error {
+		for _, fn := range initialize {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return root
+}
+
+// withInitializers makes a command and its sub-commands call init before they run.
+//
+// Cobra only calls the persistent pre-run hook of the nearest command that has one,
+// so the hooks of all sub-commands that have their own are wrapped, too.
+func withInitializers(cmd *cobra.Command, isRoot bool, init func() error) {
+	if isRoot || cmd.PersistentPreRunE != nil || cmd.PersistentPreRun != nil {
+		preRunE, preRun := cmd.PersistentPreRunE, cmd.PersistentPreRun
+		cmd.PersistentPreRun = nil
+		cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+			if err := init(); err != nil {
+				return err
+			}
+			if preRunE != nil {
+				return preRunE(c, args)
+			}
+			if preRun != nil {
+				preRun(c, args)
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		withInitializers(sub, false, init)
+	}
+}
+
+// Run the CLI app.
+func (a *App) Run() {
+	root := a.Command()
+	if err := root.Execute(); err != nil {
+		a.exit(root, err)
+	}
+}
+
+func (a *App) exit(root *cobra.Command, err error) {
+	fmt.Printf("ERROR: %s\n", err.Error())
+	fmt.Printf("\nRun `%s -h` for help!\n\n", root.Name())
+	os.Exit(1)
+}
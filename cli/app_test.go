@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAppFiles(t *testing.T) {
+	custom := New().WithFiles(Files{Observers: "custom.json"}).Command()
+	standard := New().Command()
+
+	tests := []struct {
+		name     string
+		root     *cobra.Command
+		expected string
+	}{
+		{"custom", custom, "custom.json"},
+		{"standard", standard, defaultFiles.Observers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if def := tt.root.Flag("observers").NoOptDefVal; def != tt.expected {
+				t.Errorf("expected default observers file %s, got %s", tt.expected, def)
+			}
+		})
+	}
+}
+
+func TestAppInitialize(t *testing.T) {
+	tests := []struct {
+		name  string
+		cmd   *cobra.Command
+		err   error
+		calls int
+	}{
+		{"plain", &cobra.Command{Use: "sub", RunE: func(cmd *cobra.Command, args []string) error { return nil }}, nil, 1},
+		{"own hook", &cobra.Command{
+			Use:               "sub",
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+			RunE:              func(cmd *cobra.Command, args []string) error { return nil },
+		}, nil, 1},
+		{"error", &cobra.Command{Use: "sub", RunE: func(cmd *cobra.Command, args []string) error { return nil }}, fmt.Errorf("failed"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			root := New().
+				AddCommand(tt.cmd).
+				OnInitialize(func() error {
+					calls++
+					return tt.err
+				}).
+				Command()
+			root.SetArgs([]string{"sub"})
+			err := root.Execute()
+			if err != tt.err {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+			if calls != tt.calls {
+				t.Errorf("expected %d initializer calls, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
@@ -4,54 +4,33 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand/v2"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
-	"strings"
-	"time"
 
-	"github.com/mlange-42/ark-tools/app"
-	"github.com/mlange-42/beecs-cli/internal/run"
 	"github.com/mlange-42/beecs-cli/internal/util"
 	"github.com/mlange-42/beecs/experiment"
 	"github.com/mlange-42/beecs/params"
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
-)
-
-const (
-	parametersFile = "parameters.json"
-	observersFile  = "observers.json"
-	experimentFile = "experiment.json"
-	systemsFile    = "systems.json"
 )
 
 // Run the CLI app.
+//
+// See [New] for customizing the app for derived models.
 func Run() {
-	if err := rootCommand().Execute(); err != nil {
-		fmt.Printf("ERROR: %s\n", err.Error())
-		fmt.Print("\nRun `beecs -h` for help!\n\n")
-		os.Exit(1)
-	}
+	New().Run()
 }
 
-// rootCommand sets up the CLI
-func rootCommand() *cobra.Command {
-	var dir string
-	var outDir string
-	var paramFiles []string
-	var expFile string
-	var obsFile string
-	var sysFile string
+// rootCommand sets up the CLI, with the given default file names.
+func rootCommand(files Files) *cobra.Command {
+	var flags experimentFlags
 	var speed float64
 	var threads int
-	var runs int
-	var overwrite []string
-	var seed int
-	var indicesStr string
+	var renderDir string
+	var serveAddr string
+	var editParams bool
 
 	var root cobra.Command
 	root = cobra.Command{
@@ -64,126 +43,44 @@ func rootCommand() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(paramFiles) == 0 {
+			if len(flags.paramFiles) == 0 {
 				_ = cmd.Help()
 				os.Exit(0)
 			}
 
-			flagUsed := map[string]bool{}
-			root.Flags().Visit(func(f *pflag.Flag) {
-				flagUsed[f.Name] = true
-			})
-
-			rootRng := rand.New(rand.NewPCG(0, uint64(time.Now().UTC().Nanosecond())))
-
-			if outDir == "" {
-				outDir = dir
-			}
-
-			p := params.CustomParams{
-				Parameters: params.Default(),
-			}
-			for _, f := range paramFiles {
-				err := p.FromJSONFile(path.Join(dir, f))
-				if err != nil {
-					return err
-				}
-			}
-			p.Parameters.WorkingDirectory.Path = dir
-
-			var exp experiment.Experiment
-			var rng *rand.Rand
-			var err error
-			if flagUsed["experiment"] {
-				exp, rng, err = util.ExperimentFromFile(path.Join(dir, expFile), runs, seed)
-				if err != nil {
-					return err
-				}
-			} else {
-				seedUsed := uint64(seed)
-				if seed <= 0 {
-					seedUsed = rootRng.Uint64()
-				}
-				rng = rand.New(rand.NewPCG(0, seedUsed))
-				exp, err = experiment.New([]experiment.ParameterVariation{}, rng, runs)
-				if err != nil {
-					return err
-				}
-			}
-
-			var observers util.ObserversDef
-			if flagUsed["observers"] {
-				observers, err = util.ObserversDefFromFile(path.Join(dir, obsFile))
-				if err != nil {
-					return err
-				}
-			}
-
-			var systems []app.System
-			if flagUsed["systems"] {
-				systems, err = util.SystemsFromFile(path.Join(dir, sysFile))
-				if err != nil {
-					return err
-				}
-			}
-
-			overwriteParams := make([]experiment.ParameterValue, len(overwrite))
-			for i, s := range overwrite {
-				parts := strings.Split(s, "=")
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid syntax in option --overwrite (-x)")
-				}
-				overwriteParams[i] = experiment.ParameterValue{
-					Parameter: parts[0],
-					Value:     parts[1],
-				}
-			}
-
-			indices, err := util.ParseIndices(indicesStr)
+			cfg, err := flags.config(cmd)
 			if err != nil {
 				return err
 			}
+			cfg.Threads = threads
+			cfg.TPS = speed
+			cfg.RenderDir = renderDir
+			cfg.Serve = serveAddr
+			cfg.Edit = editParams
 
-			if exp.TotalRuns() <= 1 || len(indices) == 1 {
-				threads = 1
-			}
-			if threads <= 1 {
-				return run.Sequential(&p, &exp, &observers, systems, overwriteParams, outDir, speed, rng, indices)
-			}
-			return run.Parallel(&p, &exp, &observers, systems, overwriteParams, outDir, threads, speed, rng, indices)
+			return runExperiment(&cfg)
 		},
 	}
 
-	root.Flags().StringVarP(&dir, "directory", "d", ".", "Working directory")
-	root.Flags().StringVarP(&outDir, "output", "", "", "Output directory if different from working directory")
-	root.Flags().StringSliceVarP(&paramFiles, "parameters", "p", []string{parametersFile},
-		"Parameter files, processed in the given order\n")
-
-	root.Flags().StringVarP(&expFile, "experiment", "e", "",
-		"Run experiment.\n Optionally, provide an experiment file for parameter variation")
-	root.Flag("experiment").NoOptDefVal = experimentFile
-
-	root.Flags().StringVarP(&obsFile, "observers", "o", "",
-		"Run with observers.\n Optionally, provide an observers file for adding observers")
-	root.Flag("observers").NoOptDefVal = observersFile
-
-	root.Flags().StringVarP(&sysFile, "systems", "s", "",
-		"Run with custom systems.\n Optionally, provide a systems file for using custom systems\n or changing the scheduling")
-	root.Flag("systems").NoOptDefVal = systemsFile
-
-	root.Flags().IntVarP(&seed, "seed", "", 0,
-		"Overwrite experiment super random seed for seed generation.\n Default: don't overwrite.\n Use -1 to force random seeding")
-
-	root.Flags().StringSliceVarP(&overwrite, "overwrite", "x", []string{}, "Overwrite variables like key1=value1,key2=value2")
-	root.Flags().IntVarP(&runs, "runs", "r", 1, "Runs per parameter set")
+	flags.addFlags(&root, files)
 	root.Flags().IntVarP(&threads, "threads", "t", runtime.NumCPU(), "Number of threads")
 	root.Flags().Float64VarP(&speed, "tps", "", 0, "Speed limit in ticks per second. Default: 0 (unlimited)")
-	root.Flags().StringVarP(&indicesStr, "index", "i", "", "Only run the given list or range of indices.\nExample: '2-5,8,12'. Default: all")
+	root.Flags().StringVarP(&renderDir, "render-dir", "", "",
+		"Render plots and views to PNG image sequences in this directory,\n without requiring a display")
+	root.Flags().StringVarP(&serveAddr, "serve", "", "",
+		"Serve plots and views as a live dashboard in the browser at this address,\n like ':8080', instead of showing windows")
+	root.Flags().BoolVarP(&editParams, "edit", "", false,
+		"Accept parameter edits like 'params.Foragers.FlightVelocity=6.5' from the terminal\n while the simulation is paused. Edits are logged to "+editLogFile)
 
 	root.Flags().SortFlags = false
 
-	root.AddCommand(initCommand())
+	root.AddCommand(initCommand(files))
 	root.AddCommand(parametersCommand())
+	root.AddCommand(reproduceCommand())
+	root.AddCommand(verifyReproCommand(files))
+	root.AddCommand(regressCommand(files))
+	root.AddCommand(plotCommand(files))
+	root.AddCommand(reportCommand())
 
 	return &root
 }
@@ -229,7 +126,7 @@ func parametersCommand() *cobra.Command {
 	return root
 }
 
-func initCommand() *cobra.Command {
+func initCommand(files Files) *cobra.Command {
 	var dir string
 
 	root := &cobra.Command{
@@ -242,9 +139,9 @@ func initCommand() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			parFile := path.Join(dir, parametersFile)
-			obsFile := path.Join(dir, observersFile)
-			expFile := path.Join(dir, experimentFile)
+			parFile := path.Join(dir, files.Parameters)
+			obsFile := path.Join(dir, files.Observers)
+			expFile := path.Join(dir, files.Experiment)
 
 			if fileExists(parFile) {
 				return fmt.Errorf("parameter file '%s' already exists", parFile)
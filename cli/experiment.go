@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/beecs-cli/internal/edit"
+	"github.com/mlange-42/beecs-cli/internal/run"
+	"github.com/mlange-42/beecs-cli/internal/serve"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/params"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	manifestFile = "manifest.json"
+	editLogFile  = "parameter-edits.csv"
+)
+
+// experimentFlags holds the command line flags for setting up an experiment.
+type experimentFlags struct {
+	dir        string
+	outDir     string
+	paramFiles []string
+	expFile    string
+	obsFile    string
+	sysFile    string
+	hooksFile  string
+	runs       int
+	overwrite  []string
+	seed       int
+	indicesStr string
+
+	fromSnapshot string
+	saveSnapshot string
+	snapshotTick int
+}
+
+// addFlags adds the flags to a command, with the given default file names.
+func (f *experimentFlags) addFlags(cmd *cobra.Command, files Files) {
+	cmd.Flags().StringVarP(&f.dir, "directory", "d", ".", "Working directory")
+	cmd.Flags().StringVarP(&f.outDir, "output", "", "", "Output directory if different from working directory")
+	cmd.Flags().StringSliceVarP(&f.paramFiles, "parameters", "p", []string{files.Parameters},
+		"Parameter files, processed in the given order\n")
+
+	cmd.Flags().StringVarP(&f.expFile, "experiment", "e", "",
+		"Run experiment.\n Optionally, provide an experiment file for parameter variation")
+	cmd.Flag("experiment").NoOptDefVal = files.Experiment
+
+	cmd.Flags().StringVarP(&f.obsFile, "observers", "o", "",
+		"Run with observers.\n Optionally, provide an observers file for adding observers")
+	cmd.Flag("observers").NoOptDefVal = files.Observers
+
+	cmd.Flags().StringVarP(&f.sysFile, "systems", "s", "",
+		"Run with custom systems.\n Optionally, provide a systems file for using custom systems\n or changing the scheduling")
+	cmd.Flag("systems").NoOptDefVal = files.Systems
+
+	cmd.Flags().StringVarP(&f.hooksFile, "hooks", "", "",
+		"Run with lifecycle hooks.\n Optionally, provide a hooks file for calling registered hooks during each run")
+	cmd.Flag("hooks").NoOptDefVal = files.Hooks
+
+	cmd.Flags().IntVarP(&f.seed, "seed", "", 0,
+		"Overwrite experiment super random seed for seed generation.\n Default: don't overwrite.\n Use -1 to force random seeding")
+
+	cmd.Flags().StringSliceVarP(&f.overwrite, "overwrite", "x", []string{}, "Overwrite variables like key1=value1,key2=value2")
+	cmd.Flags().IntVarP(&f.runs, "runs", "r", 1, "Runs per parameter set")
+	cmd.Flags().StringVarP(&f.indicesStr, "index", "i", "", "Only run the given list or range of indices.\nExample: '2-5,8,12'. Default: all")
+
+	cmd.Flags().StringVarP(&f.fromSnapshot, "from-snapshot", "", "",
+		"Start all runs from a world snapshot file.\n Parameter values of the runs are applied after loading the snapshot")
+	cmd.Flags().StringVarP(&f.saveSnapshot, "save-snapshot", "", "",
+		"Save a world snapshot of each run to this file in the output directory,\n with the run index appended. Requires --snapshot-tick")
+	cmd.Flags().IntVarP(&f.snapshotTick, "snapshot-tick", "", 0, "Tick to save world snapshots at, for --save-snapshot")
+}
+
+// config reads all input files and creates an experiment configuration.
+func (f *experimentFlags) config(cmd *cobra.Command) (experimentConfig, error) {
+	flagUsed := map[string]bool{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flagUsed[f.Name] = true
+	})
+
+	rootRng := rand.New(rand.NewPCG(0, uint64(time.Now().UTC().Nanosecond())))
+
+	outDir := f.outDir
+	if outDir == "" {
+		outDir = f.dir
+	}
+
+	cfg := experimentConfig{
+		Dir:    f.dir,
+		OutDir: outDir,
+		Runs:   f.runs,
+	}
+
+	cfg.Params = params.CustomParams{
+		Parameters: params.Default(),
+	}
+	for _, file := range f.paramFiles {
+		err := cfg.Params.FromJSONFile(path.Join(f.dir, file))
+		if err != nil {
+			return cfg, err
+		}
+	}
+	cfg.Params.Parameters.WorkingDirectory.Path = f.dir
+	cfg.InputFiles = append(cfg.InputFiles, f.paramFiles...)
+
+	var err error
+	if flagUsed["experiment"] {
+		cfg.Experiment, err = util.ExperimentDefFromFile(path.Join(f.dir, f.expFile))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.InputFiles = append(cfg.InputFiles, f.expFile)
+		if f.seed == 0 {
+			cfg.SuperSeed = uint64(cfg.Experiment.Seed)
+		} else if f.seed < 0 {
+			cfg.SuperSeed = uint64(rand.Uint32())
+		} else {
+			cfg.SuperSeed = uint64(f.seed)
+		}
+	} else {
+		cfg.SuperSeed = uint64(f.seed)
+		if f.seed <= 0 {
+			cfg.SuperSeed = rootRng.Uint64()
+		}
+	}
+
+	if flagUsed["observers"] {
+		cfg.Observers, err = util.ObserversDefFromFile(path.Join(f.dir, f.obsFile))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.InputFiles = append(cfg.InputFiles, f.obsFile)
+	}
+
+	if flagUsed["systems"] {
+		cfg.Systems, err = util.SystemNamesFromFile(path.Join(f.dir, f.sysFile))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.InputFiles = append(cfg.InputFiles, f.sysFile)
+	}
+
+	if flagUsed["hooks"] {
+		cfg.Hooks, err = util.HooksDefFromFile(path.Join(f.dir, f.hooksFile))
+		if err != nil {
+			return cfg, err
+		}
+		cfg.InputFiles = append(cfg.InputFiles, f.hooksFile)
+	}
+
+	cfg.Overwrite = make([]experiment.ParameterValue, len(f.overwrite))
+	for i, s := range f.overwrite {
+		parts := strings.Split(s, "=")
+		if len(parts) != 2 {
+			return cfg, fmt.Errorf("invalid syntax in option --overwrite (-x)")
+		}
+		cfg.Overwrite[i] = experiment.ParameterValue{
+			Parameter: parts[0],
+			Value:     parts[1],
+		}
+	}
+
+	cfg.Indices, err = util.ParseIndices(f.indicesStr)
+	if err != nil {
+		return cfg, err
+	}
+
+	if f.saveSnapshot != "" && !flagUsed["snapshot-tick"] {
+		return cfg, fmt.Errorf("option --save-snapshot requires --snapshot-tick")
+	}
+	cfg.SaveSnapshot = f.saveSnapshot
+	cfg.SnapshotTick = f.snapshotTick
+	if f.fromSnapshot != "" {
+		cfg.FromSnapshot = f.fromSnapshot
+		cfg.InputFiles = append(cfg.InputFiles, f.fromSnapshot)
+	}
+
+	return cfg, nil
+}
+
+// experimentConfig holds the fully resolved configuration of an experiment.
+type experimentConfig struct {
+	Dir          string
+	OutDir       string
+	Params       params.CustomParams
+	Experiment   util.ExperimentJs
+	Runs         int
+	SuperSeed    uint64
+	Observers    util.ObserversDef
+	Systems      []string
+	Hooks        []util.HookDef
+	Overwrite    []experiment.ParameterValue
+	Indices      []int
+	Threads      int
+	TPS          float64
+	NoUI         bool     // Never show UI, even for single runs.
+	RenderDir    string   // Directory for headless rendering of plots and views. Empty for none.
+	Serve        string   // Address for serving a live dashboard, like ":8080". Empty for none.
+	Edit         bool     // Accept live parameter edits from the terminal.
+	FromSnapshot string   // Snapshot file relative to Dir, to start runs from. Empty for none.
+	SaveSnapshot string   // Snapshot file relative to OutDir, to save snapshots to. Empty for none.
+	SnapshotTick int      // Tick to save snapshots at.
+	InputFiles   []string // Input files relative to Dir, for the manifest.
+}
+
+// runExperiment runs an experiment and writes its manifest to the output directory.
+func runExperiment(cfg *experimentConfig) error {
+	started := time.Now()
+
+	exp, rng, err := cfg.Experiment.Build(cfg.Runs, cfg.SuperSeed)
+	if err != nil {
+		return err
+	}
+
+	var systems []app.System
+	if len(cfg.Systems) > 0 {
+		systems, err = util.SystemsFromNames(cfg.Systems)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest, err := newManifest(cfg, started)
+	if err != nil {
+		return err
+	}
+
+	snapshots := snapshot.Options{File: cfg.SaveSnapshot, Tick: int64(cfg.SnapshotTick)}
+	if cfg.FromSnapshot != "" {
+		snapshots.From, err = snapshot.ReadFile(path.Join(cfg.Dir, cfg.FromSnapshot))
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.RenderDir != "" {
+		for _, name := range cfg.Observers.UnrenderableViews() {
+			fmt.Printf("WARNING: view '%s' does not support headless rendering and is skipped\n", name)
+		}
+	}
+
+	threads := cfg.Threads
+	if exp.TotalRuns() <= 1 || len(cfg.Indices) == 1 {
+		threads = 1
+	}
+
+	var server *serve.Server
+	noUI := cfg.NoUI
+	if cfg.Serve != "" {
+		server = serve.NewServer(cfg.Serve)
+		if err := server.Start(); err != nil {
+			return err
+		}
+		defer server.Close()
+		fmt.Printf("Serving live dashboard at %s\n", server.URL())
+		for _, name := range cfg.Observers.UnrenderableViews() {
+			fmt.Printf("WARNING: view '%s' does not support the live dashboard and is skipped\n", name)
+		}
+		// The dashboard shows runs one after another, without any OpenGL windows.
+		threads = 1
+		noUI = true
+	}
+	if !noUI && threads > 1 && cfg.Observers.MultiRunPlots() {
+		// Live plots across runs require the runs to be performed one after another.
+		fmt.Println("Showing live plots across runs, running sequentially")
+		threads = 1
+	}
+
+	var edits *edit.Session
+	if cfg.Edit {
+		runs := exp.TotalRuns()
+		if len(cfg.Indices) > 0 {
+			runs = len(cfg.Indices)
+		}
+		withUI := server != nil || (!noUI && (runs <= 1 || cfg.Observers.MultiRunPlots()))
+		if withUI {
+			if err := os.MkdirAll(cfg.OutDir, os.ModePerm); err != nil {
+				return err
+			}
+			edits, err = edit.NewSession(os.Stdin, path.Join(cfg.OutDir, editLogFile))
+			if err != nil {
+				return err
+			}
+			defer edits.Close()
+			manifest.EditLog = editLogFile
+		} else {
+			fmt.Println("WARNING: option --edit requires live windows or the dashboard, and is ignored")
+		}
+	}
+
+	if threads <= 1 {
+		err = run.Sequential(&cfg.Params, &exp, &cfg.Observers, systems, cfg.Overwrite, cfg.Hooks, snapshots, cfg.Experiment.Fork, cfg.OutDir, cfg.TPS, rng, cfg.Indices, noUI, cfg.RenderDir, server, edits)
+	} else {
+		err = run.Parallel(&cfg.Params, &exp, &cfg.Observers, systems, cfg.Overwrite, cfg.Hooks, snapshots, cfg.Experiment.Fork, cfg.OutDir, threads, cfg.TPS, rng, cfg.Indices, cfg.RenderDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	manifest.Finished = time.Now()
+	manifest.Duration = manifest.Finished.Sub(started).Seconds()
+
+	if err := os.MkdirAll(cfg.OutDir, os.ModePerm); err != nil {
+		return err
+	}
+	return writeJSON(path.Join(cfg.OutDir, manifestFile), &manifest)
+}
+
+// newManifest creates a manifest for an experiment that is about to start.
+func newManifest(cfg *experimentConfig, started time.Time) (util.Manifest, error) {
+	js, err := cfg.Params.ToJSON()
+	if err != nil {
+		return util.Manifest{}, err
+	}
+	resolved, err := util.ResolveParameters(js, cfg.Overwrite)
+	if err != nil {
+		fmt.Printf("WARNING: can't resolve parameter overwrites for the manifest: %s\n", err.Error())
+		resolved = js
+	}
+
+	referenced, err := util.ReferencedFiles(js, cfg.Dir)
+	if err != nil {
+		return util.Manifest{}, err
+	}
+	hashes, err := util.HashFiles(cfg.Dir, append(append([]string{}, cfg.InputFiles...), referenced...))
+	if err != nil {
+		return util.Manifest{}, err
+	}
+
+	workDir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return util.Manifest{}, err
+	}
+	outDir, err := filepath.Abs(cfg.OutDir)
+	if err != nil {
+		return util.Manifest{}, err
+	}
+
+	return util.Manifest{
+		Versions:         util.GetVersions(),
+		CommandLine:      os.Args,
+		WorkingDirectory: workDir,
+		OutputDirectory:  outDir,
+		Parameters:       resolved,
+		Overwrite:        cfg.Overwrite,
+		Experiment:       cfg.Experiment,
+		Runs:             cfg.Runs,
+		SuperSeed:        cfg.SuperSeed,
+		Indices:          cfg.Indices,
+		Observers:        cfg.Observers,
+		Systems:          cfg.Systems,
+		Hooks:            cfg.Hooks,
+		FromSnapshot:     cfg.FromSnapshot,
+		SaveSnapshot:     cfg.SaveSnapshot,
+		SnapshotTick:     cfg.SnapshotTick,
+		Threads:          cfg.Threads,
+		TPS:              cfg.TPS,
+		RenderDir:        cfg.RenderDir,
+		InputFiles:       hashes,
+		Host:             util.GetHost(),
+		Started:          started,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func plotCommand(files Files) *cobra.Command {
+	var dir string
+	var outDir string
+	var obsFile string
+
+	root := &cobra.Command{
+		Use:   "plot",
+		Short: "Creates static plots from experiment output.",
+		Long: `Creates static plots from experiment output.
+
+Reads the CSV files written by tables and creates static plots as PNG, SVG or PDF files.
+Plots are configured in the observers file:
+
+ - time series plots with property 'Static', showing single runs or replicate envelopes
+ - parameter-response plots in section 'ResponsePlots'
+
+Plot files are relative to the output directory.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			observers, err := util.ObserversDefFromFile(path.Join(dir, obsFile))
+			if err != nil {
+				return err
+			}
+			if outDir == "" {
+				outDir = dir
+			}
+			created, err := observers.SaveStaticPlots(outDir)
+			if err != nil {
+				return err
+			}
+			if len(created) == 0 {
+				fmt.Println("No static plots configured")
+			}
+			for _, f := range created {
+				fmt.Printf("Created %s\n", f)
+			}
+			return nil
+		},
+	}
+	root.Flags().StringVarP(&dir, "directory", "d", ".", "Working directory")
+	root.Flags().StringVarP(&outDir, "output", "", "", "Output directory of the experiment, if different from working directory")
+	root.Flags().StringVarP(&obsFile, "observers", "o", files.Observers, "Observers file")
+
+	root.Flags().SortFlags = false
+
+	return root
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/spf13/cobra"
+)
+
+const regressionFile = "regression.json"
+
+func regressCommand(files Files) *cobra.Command {
+	var flags experimentFlags
+	var baseline string
+	var regFile string
+	var threads int
+	var update bool
+
+	root := &cobra.Command{
+		Use:   "regress",
+		Short: "Compares experiment output to a stored baseline.",
+		Long: `Compares experiment output to a stored baseline.
+
+Runs the experiment and compares all table output to the CSV files in the baseline directory,
+using per-column absolute and relative tolerances.
+Further, Kolmogorov-Smirnov tests across replicates can be performed per parameter set.
+Tolerances and tests are configured in an optional regression file.
+
+Exits with an error if any comparison or test fails.
+Use option --update to write the current output as new baseline.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("observers") {
+				return fmt.Errorf("regression testing requires table output; use option --observers (-o)")
+			}
+			cfg, err := flags.config(cmd)
+			if err != nil {
+				return err
+			}
+			cfg.NoUI = true
+			cfg.Threads = threads
+
+			if update {
+				cfg.OutDir = baseline
+				if err := runExperiment(&cfg); err != nil {
+					return err
+				}
+				fmt.Printf("Updated baseline in '%s'\n", baseline)
+				return nil
+			}
+
+			var def util.RegressionDef
+			if cmd.Flags().Changed("tolerances") {
+				def, err = util.RegressionDefFromFile(path.Join(flags.dir, regFile))
+				if err != nil {
+					return err
+				}
+			}
+
+			if flags.outDir == "" {
+				tempDir, err := os.MkdirTemp("", "beecs-regress-*")
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(tempDir)
+				cfg.OutDir = tempDir
+			}
+			if err := runExperiment(&cfg); err != nil {
+				return err
+			}
+
+			passed, err := regress(&cfg.Observers, &def, cfg.OutDir, baseline)
+			if err != nil {
+				return err
+			}
+			if !passed {
+				return fmt.Errorf("output deviates from baseline")
+			}
+			fmt.Println("PASSED all comparisons")
+			return nil
+		},
+	}
+
+	flags.addFlags(root, files)
+	root.Flags().StringVarP(&baseline, "baseline", "b", "", "Baseline directory, relative to the current directory")
+	root.Flags().StringVarP(&regFile, "tolerances", "", "",
+		"Use tolerances and tests.\n Optionally, provide a regression file")
+	root.Flag("tolerances").NoOptDefVal = regressionFile
+	root.Flags().IntVarP(&threads, "threads", "t", runtime.NumCPU(), "Number of threads")
+	root.Flags().BoolVarP(&update, "update", "u", false, "Write output to the baseline directory instead of comparing")
+	_ = root.MarkFlagRequired("baseline")
+
+	root.Flags().SortFlags = false
+
+	return root
+}
+
+// regress compares all output files to the baseline and prints a report.
+func regress(observers *util.ObserversDef, def *util.RegressionDef, dir, baseline string) (bool, error) {
+	sep := observers.CsvSeparator
+	files := observers.OutputFiles()
+
+	var sets, baseSets map[string]string
+	if files[0] != "" {
+		params, err := util.ReadCsv(path.Join(dir, files[0]), sep)
+		if err != nil {
+			return false, err
+		}
+		baseParams, err := util.ReadCsv(path.Join(baseline, files[0]), sep)
+		if err != nil {
+			return false, err
+		}
+		sets = util.ParameterSets(&params)
+		baseSets = util.ParameterSets(&baseParams)
+	}
+
+	passed := true
+	for i, f := range files {
+		if f == "" {
+			continue
+		}
+		table, err := util.ReadCsv(path.Join(dir, f), sep)
+		if err != nil {
+			return false, err
+		}
+		base, err := util.ReadCsv(path.Join(baseline, f), sep)
+		if err != nil {
+			return false, err
+		}
+		tabDef := def.Table(f)
+
+		if !tabDef.NoPointwise {
+			var ignore []string
+			if i == 0 {
+				ignore = []string{"Started", "Finished"}
+			}
+			count, first, err := util.CompareTolerance(&table, &base, &tabDef, def.Tolerance, ignore)
+			if err != nil {
+				return false, err
+			}
+			if count == 0 {
+				fmt.Printf("PASSED %s\n", f)
+			} else {
+				passed = false
+				fmt.Printf("FAILED %s: %d difference(s), first: %s\n", f, count, first.String())
+			}
+		}
+
+		for _, test := range tabDef.Tests {
+			results, err := util.TestReplicates(&table, &base, sets, baseSets, &test)
+			if err != nil {
+				return false, fmt.Errorf("in KS test for %s: %s", f, err.Error())
+			}
+			for _, r := range results {
+				status := "PASSED"
+				if !r.Pass {
+					status = "FAILED"
+					passed = false
+				}
+				fmt.Printf("%s %s, KS test of '%s' [%s]: D=%.4f, p=%.4f (n=%d, m=%d)\n",
+					status, f, r.Column, r.Set, r.D, r.P, r.N, r.M)
+			}
+		}
+	}
+	return passed, nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/spf13/cobra"
+)
+
+const reportFile = "report.html"
+
+func reportCommand() *cobra.Command {
+	var dir string
+	var file string
+
+	root := &cobra.Command{
+		Use:   "report",
+		Short: "Creates a self-contained HTML report of an experiment.",
+		Long: `Creates a self-contained HTML report of an experiment.
+
+Reads the manifest and the table output from the experiment's output directory.
+The report contains the parameter design, run times, summary plots for all tables,
+extinction statistics from obs.Extinction tables, and all input files.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := util.ManifestFromFile(path.Join(dir, manifestFile))
+			if err != nil {
+				return err
+			}
+			report, err := util.NewReport(&m, dir)
+			if err != nil {
+				return err
+			}
+
+			out := path.Join(dir, file)
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			if err := report.Write(f); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("Created report '%s'\n", out)
+			return nil
+		},
+	}
+	root.Flags().StringVarP(&dir, "directory", "d", ".", "Output directory of the experiment, containing the manifest")
+	root.Flags().StringVarP(&file, "file", "f", reportFile, "Report file, relative to the output directory")
+
+	root.Flags().SortFlags = false
+
+	return root
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/params"
+	"github.com/spf13/cobra"
+)
+
+// reproducedSuffix is appended to the original output directory for the default output directory of reproduce.
+const reproducedSuffix = "-reproduced"
+
+func reproduceCommand() *cobra.Command {
+	var dir string
+	var outDir string
+	var force bool
+
+	root := &cobra.Command{
+		Use:   "reproduce MANIFEST",
+		Short: "Reruns an experiment from its manifest file.",
+		Long: `Reruns an experiment from its manifest file.
+
+Parameters, experiment, observers and systems are taken from the manifest.
+Input files like weather or patch files are checked against the recorded hashes.
+
+Output is written to a sibling of the original output directory, with suffix '-reproduced'.
+Writing into the original output directory requires --force.`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := util.ManifestFromFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			if dir == "" {
+				dir = m.WorkingDirectory
+			}
+			if outDir == "" {
+				outDir = filepath.Clean(m.OutputDirectory) + reproducedSuffix
+			}
+			if err := checkOutputDirectory(&m, outDir, force); err != nil {
+				return err
+			}
+
+			if err := checkVersions(&m, force); err != nil {
+				return err
+			}
+			if err := checkEditLog(&m, force); err != nil {
+				return err
+			}
+			if err := checkInputFiles(&m, dir, force); err != nil {
+				return err
+			}
+
+			cfg := experimentConfig{
+				Dir:          dir,
+				OutDir:       outDir,
+				Experiment:   m.Experiment,
+				Runs:         m.Runs,
+				SuperSeed:    m.SuperSeed,
+				Observers:    m.Observers,
+				Systems:      m.Systems,
+				Hooks:        m.Hooks,
+				FromSnapshot: m.FromSnapshot,
+				SaveSnapshot: m.SaveSnapshot,
+				SnapshotTick: m.SnapshotTick,
+				Overwrite:    m.Overwrite,
+				Indices:      m.Indices,
+				Threads:      m.Threads,
+				TPS:          m.TPS,
+				RenderDir:    m.RenderDir,
+			}
+			for f := range m.InputFiles {
+				if fileExists(filepath.Join(dir, f)) {
+					cfg.InputFiles = append(cfg.InputFiles, f)
+				}
+			}
+			sort.Strings(cfg.InputFiles)
+
+			cfg.Params = params.CustomParams{
+				Parameters: params.Default(),
+			}
+			if err := cfg.Params.FromJSON(m.Parameters); err != nil {
+				return err
+			}
+			cfg.Params.Parameters.WorkingDirectory.Path = dir
+
+			return runExperiment(&cfg)
+		},
+	}
+	root.Flags().StringVarP(&dir, "directory", "d", "", "Working directory. Default: as in the manifest")
+	root.Flags().StringVarP(&outDir, "output", "", "", "Output directory. Default: the manifest's output directory with suffix '"+reproducedSuffix+"'")
+	root.Flags().BoolVarP(&force, "force", "f", false, "Run even if versions or input files differ from the manifest,\n edits were applied, or output would overwrite the original output")
+
+	return root
+}
+
+// checkOutputDirectory checks that the output does not overwrite the original output of the manifest.
+func checkOutputDirectory(m *util.Manifest, outDir string, force bool) error {
+	abs, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	if abs != filepath.Clean(m.OutputDirectory) {
+		return nil
+	}
+	msg := fmt.Sprintf("output directory '%s' is the original output directory of the manifest", outDir)
+	if force {
+		fmt.Printf("WARNING: %s\n", msg)
+		return nil
+	}
+	return fmt.Errorf("%s; use another --output, or --force to overwrite", msg)
+}
+
+// checkEditLog checks that no live parameter edits were applied in the original experiment,
+// as they are not replayed.
+func checkEditLog(m *util.Manifest, force bool) error {
+	if m.EditLog == "" {
+		return nil
+	}
+	msg := fmt.Sprintf("live parameter edits were applied in the original experiment (see '%s'), and are not replayed", m.EditLog)
+	if force {
+		fmt.Printf("WARNING: %s\n", msg)
+		return nil
+	}
+	return fmt.Errorf("%s; use --force to run anyway", msg)
+}
+
+func checkVersions(m *util.Manifest, force bool) error {
+	v := util.GetVersions()
+	if v.BeecsCli == m.Versions.BeecsCli && v.Beecs == m.Versions.Beecs {
+		return nil
+	}
+	msg := fmt.Sprintf("versions differ from the manifest: beecs-cli %s (manifest: %s), beecs %s (manifest: %s)",
+		v.BeecsCli, m.Versions.BeecsCli, v.Beecs, m.Versions.Beecs)
+	if force {
+		fmt.Printf("WARNING: %s\n", msg)
+		return nil
+	}
+	return fmt.Errorf("%s; use --force to run anyway", msg)
+}
+
+func checkInputFiles(m *util.Manifest, dir string, force bool) error {
+	for f, hash := range m.InputFiles {
+		actual, err := util.HashFile(filepath.Join(dir, f))
+		if err != nil {
+			if force {
+				fmt.Printf("WARNING: %s\n", err.Error())
+				continue
+			}
+			return err
+		}
+		if actual == hash {
+			continue
+		}
+		msg := fmt.Sprintf("input file '%s' changed since the manifest was written", f)
+		if force {
+			fmt.Printf("WARNING: %s\n", msg)
+			continue
+		}
+		return fmt.Errorf("%s; use --force to run anyway", msg)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func verifyReproCommand(files Files) *cobra.Command {
+	var flags experimentFlags
+	var threads []int
+	var keep bool
+
+	root := &cobra.Command{
+		Use:   "verify-repro",
+		Short: "Verifies that an experiment is reproducible.",
+		Long: `Verifies that an experiment is reproducible.
+
+Runs the experiment repeatedly, with the given numbers of threads,
+and compares all table output and the event log bit-for-bit.
+Use option --index to restrict verification to a subset of runs.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("observers") {
+				return fmt.Errorf("verification requires table output; use option --observers (-o)")
+			}
+			cfg, err := flags.config(cmd)
+			if err != nil {
+				return err
+			}
+			cfg.NoUI = true
+
+			if len(threads) == 0 {
+				return fmt.Errorf("at least one value required for option --threads")
+			}
+			if len(threads) == 1 {
+				threads = append(threads, threads[0])
+			}
+
+			tempDir, err := os.MkdirTemp("", "beecs-verify-*")
+			if err != nil {
+				return err
+			}
+			if keep {
+				fmt.Printf("Keeping output in '%s'\n", tempDir)
+			} else {
+				defer os.RemoveAll(tempDir)
+			}
+
+			dirs := make([]string, len(threads))
+			for i, t := range threads {
+				dirs[i] = path.Join(tempDir, fmt.Sprintf("rep-%d", i))
+				fmt.Printf("Repetition %d with %d thread(s)\n", i, t)
+				cfg.OutDir = dirs[i]
+				cfg.Threads = t
+				if err := runExperiment(&cfg); err != nil {
+					return err
+				}
+			}
+
+			files := cfg.Observers.OutputFiles()
+			failed := false
+			for i, f := range files {
+				if f == "" {
+					continue
+				}
+				var ignore []string
+				if i == 0 {
+					ignore = []string{"Started", "Finished"}
+				}
+				identical, err := compareRepetitions(dirs, f, ignore, func(p string) (util.CsvTable, error) {
+					return util.ReadCsv(p, cfg.Observers.CsvSeparator)
+				})
+				if err != nil {
+					return err
+				}
+				failed = failed || !identical
+			}
+			if f := cfg.Observers.EventLog.File; f != "" {
+				identical, err := compareRepetitions(dirs, f, nil, func(p string) (util.CsvTable, error) {
+					return util.ReadEventLog(p, cfg.Observers.CsvSeparator)
+				})
+				if err != nil {
+					return err
+				}
+				failed = failed || !identical
+			}
+
+			if failed {
+				return fmt.Errorf("experiment is not reproducible")
+			}
+			fmt.Println("Experiment is reproducible")
+			return nil
+		},
+	}
+
+	flags.addFlags(root, files)
+	root.Flags().IntSliceVarP(&threads, "threads", "t", []int{1, runtime.NumCPU()},
+		"Numbers of threads, one repetition per value.\n A single value is repeated twice")
+	root.Flags().BoolVarP(&keep, "keep", "k", false, "Keep the output of all repetitions")
+
+	root.Flags().SortFlags = false
+
+	return root
+}
+
+// compareRepetitions compares an output file of all repetitions to the first one, and prints the result.
+func compareRepetitions(dirs []string, file string, ignore []string, read func(path string) (util.CsvTable, error)) (bool, error) {
+	ref, err := read(path.Join(dirs[0], file))
+	if err != nil {
+		return false, err
+	}
+	for j := 1; j < len(dirs); j++ {
+		other, err := read(path.Join(dirs[j], file))
+		if err != nil {
+			return false, err
+		}
+		if diff := util.CompareTables(&ref, &other, ignore); diff != nil {
+			fmt.Printf("DIFFERENT %s, repetition 0 vs. %d: %s\n", file, j, diff.String())
+			return false, nil
+		}
+	}
+	fmt.Printf("IDENTICAL %s\n", file)
+	return true, nil
+}
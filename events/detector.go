@@ -0,0 +1,131 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/comp"
+	"github.com/mlange-42/beecs/globals"
+	"github.com/mlange-42/beecs/params"
+)
+
+// Detector is a system that detects built-in events at the end of each tick and emits them to the [Log].
+//
+// Events are emitted when a condition becomes true, not on every tick it holds.
+// For runs continued from a snapshot, i.e. if the first update is not at tick 0,
+// conditions that hold at the first update are taken as the state without emitting events,
+// as they were already detected before the snapshot was taken.
+//
+// BroodCareFailure uses the nursing capacity like beecs' brood care:
+// in-hive workers plus the nursing contribution of foragers, times the maximum brood-to-nurse ratio.
+type Detector struct {
+	Events            []string // Event types to detect. Default: all.
+	SwarmingThreshold float64  // Number of adult bees for Swarming events. Swarming is not detected if zero.
+
+	enabled map[string]bool
+	log     ecs.Resource[Log]
+	tick    ecs.Resource[resource.Tick]
+	stores  ecs.Resource[globals.Stores]
+	pop     ecs.Resource[globals.PopulationStats]
+	nursing ecs.Resource[params.Nursing]
+	patches *ecs.Filter2[comp.Coords, comp.Resource]
+
+	state    map[string]bool     // Whether the condition of an event type holds.
+	depleted map[ecs.Entity]bool // Depleted patches.
+	updated  bool                // Whether the system was updated since initialization.
+}
+
+// NewDetector creates a new detector for the given event types, and checks them for validity.
+func NewDetector(events []string, swarmingThreshold float64) (*Detector, error) {
+	for _, e := range events {
+		switch e {
+		case Extinction, HoneyDepletion, PollenDepletion, BroodCareFailure, Swarming, PatchDepletion:
+		default:
+			return nil, fmt.Errorf("unknown event type '%s'", e)
+		}
+	}
+	return &Detector{Events: events, SwarmingThreshold: swarmingThreshold}, nil
+}
+
+// Initialize the system
+func (d *Detector) Initialize(w *ecs.World) {
+	events := d.Events
+	if len(events) == 0 {
+		events = []string{Extinction, HoneyDepletion, PollenDepletion, BroodCareFailure, Swarming, PatchDepletion}
+	}
+	d.enabled = map[string]bool{}
+	for _, e := range events {
+		d.enabled[e] = true
+	}
+
+	d.log = ecs.NewResource[Log](w)
+	d.tick = ecs.NewResource[resource.Tick](w)
+	d.stores = ecs.NewResource[globals.Stores](w)
+	d.pop = ecs.NewResource[globals.PopulationStats](w)
+	d.nursing = ecs.NewResource[params.Nursing](w)
+	d.patches = ecs.NewFilter2[comp.Coords, comp.Resource](w)
+
+	d.state = map[string]bool{}
+	d.depleted = map[ecs.Entity]bool{}
+	d.updated = false
+}
+
+// Update the system
+func (d *Detector) Update(w *ecs.World) {
+	tick := d.tick.Get().Tick
+	stores := d.stores.Get()
+	pop := d.pop.Get()
+
+	emit := d.updated || tick == 0
+	d.updated = true
+
+	d.detect(tick, emit, Extinction, pop.TotalPopulation == 0, nil)
+	d.detect(tick, emit, HoneyDepletion, stores.Honey <= 0, nil)
+	d.detect(tick, emit, PollenDepletion, stores.Pollen <= 0, nil)
+	if d.enabled[BroodCareFailure] {
+		nursing := d.nursing.Get()
+		capacity := (float64(pop.WorkersInHive) + float64(pop.WorkersForagers)*nursing.ForagerNursingContribution) *
+			nursing.MaxBroodNurseRatio
+		d.detect(tick, emit, BroodCareFailure, float64(pop.TotalBrood) > capacity,
+			map[string]any{"Brood": pop.TotalBrood, "Capacity": capacity})
+	}
+	if d.SwarmingThreshold > 0 {
+		d.detect(tick, emit, Swarming, float64(pop.TotalAdults) >= d.SwarmingThreshold,
+			map[string]any{"Adults": pop.TotalAdults})
+	}
+
+	if !d.enabled[PatchDepletion] {
+		return
+	}
+	query := d.patches.Query()
+	for query.Next() {
+		coords, res := query.Get()
+		e := query.Entity()
+		depleted := (res.MaxNectar > 0 && res.Nectar <= 0) || (res.MaxPollen > 0 && res.Pollen <= 0)
+		if emit && depleted && !d.depleted[e] {
+			d.log.Get().Emit(tick, PatchDepletion, map[string]any{
+				"Patch":  e.ID(),
+				"X":      coords.X,
+				"Y":      coords.Y,
+				"Nectar": res.Nectar,
+				"Pollen": res.Pollen,
+			})
+		}
+		d.depleted[e] = depleted
+	}
+}
+
+// detect emits an event if its condition becomes true, and if emit is true.
+func (d *Detector) detect(tick int64, emit bool, tp string, condition bool, payload map[string]any) {
+	if !d.enabled[tp] {
+		return
+	}
+	if emit && condition && !d.state[tp] {
+		d.log.Get().Emit(tick, tp, payload)
+	}
+	d.state[tp] = condition
+}
+
+// Finalize the system
+func (d *Detector) Finalize(w *ecs.World) {}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/globals"
+	"github.com/mlange-42/beecs/params"
+)
+
+func TestDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		stores   globals.Stores
+		pop      globals.PopulationStats
+		expected []string
+	}{
+		{"none", globals.Stores{Honey: 1, Pollen: 1},
+			globals.PopulationStats{WorkersInHive: 10, TotalBrood: 20, TotalPopulation: 30}, nil},
+		{"honey", globals.Stores{Pollen: 1},
+			globals.PopulationStats{WorkersInHive: 10, TotalBrood: 20, TotalPopulation: 30}, []string{HoneyDepletion}},
+		{"pollen", globals.Stores{Honey: 1},
+			globals.PopulationStats{WorkersInHive: 10, TotalBrood: 20, TotalPopulation: 30}, []string{PollenDepletion}},
+		{"brood care", globals.Stores{Honey: 1, Pollen: 1},
+			globals.PopulationStats{WorkersInHive: 10, TotalBrood: 31, TotalPopulation: 41}, []string{BroodCareFailure}},
+		{"foragers nursing", globals.Stores{Honey: 1, Pollen: 1},
+			globals.PopulationStats{WorkersInHive: 10, WorkersForagers: 10, TotalBrood: 40, TotalPopulation: 60}, nil},
+		{"extinction", globals.Stores{Honey: 1, Pollen: 1},
+			globals.PopulationStats{}, []string{Extinction}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ecs.NewWorld()
+			ecs.AddResource(&w, &resource.Tick{Tick: 0})
+			ecs.AddResource(&w, &tt.stores)
+			ecs.AddResource(&w, &tt.pop)
+			ecs.AddResource(&w, &params.Nursing{MaxBroodNurseRatio: 3, ForagerNursingContribution: 0.5})
+			emitted := []string{}
+			ecs.AddResource(&w, NewLog(0, func(e Event) { emitted = append(emitted, e.Type) }))
+
+			d, err := NewDetector([]string{Extinction, HoneyDepletion, PollenDepletion, BroodCareFailure}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Initialize(&w)
+			d.Update(&w)
+			d.Update(&w)
+
+			if len(emitted) != len(tt.expected) {
+				t.Fatalf("expected events %v, got %v", tt.expected, emitted)
+			}
+			for i, e := range tt.expected {
+				if emitted[i] != e {
+					t.Errorf("expected events %v, got %v", tt.expected, emitted)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectorContinued(t *testing.T) {
+	w := ecs.NewWorld()
+	tick := resource.Tick{Tick: 100}
+	stores := globals.Stores{Honey: 1}
+	ecs.AddResource(&w, &tick)
+	ecs.AddResource(&w, &stores)
+	ecs.AddResource(&w, &globals.PopulationStats{WorkersInHive: 10, TotalPopulation: 10})
+	ecs.AddResource(&w, &params.Nursing{MaxBroodNurseRatio: 3, ForagerNursingContribution: 0.5})
+	emitted := []string{}
+	ecs.AddResource(&w, NewLog(0, func(e Event) { emitted = append(emitted, e.Type) }))
+
+	d, err := NewDetector([]string{HoneyDepletion, PollenDepletion}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Initialize(&w)
+
+	// Pollen depletion holds already at the start of the continued run.
+	d.Update(&w)
+	if len(emitted) != 0 {
+		t.Fatalf("expected no events on the first update of a continued run, got %v", emitted)
+	}
+
+	tick.Tick++
+	stores.Honey = 0
+	d.Update(&w)
+	if len(emitted) != 1 || emitted[0] != HoneyDepletion {
+		t.Errorf("expected events %v, got %v", []string{HoneyDepletion}, emitted)
+	}
+}
+
+func TestNewDetectorUnknown(t *testing.T) {
+	if _, err := NewDetector([]string{"BroodCare"}, 0); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
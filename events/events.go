@@ -0,0 +1,47 @@
+// Package events provides an event log for discrete model events, like colony extinction or depletion of stores.
+//
+// Events are emitted by systems and observers via the [Log] resource, which is present in every run:
+//
+//	log := ecs.GetResource[events.Log](world)
+//	log.Emit(tick, "MyEvent", map[string]any{"Value": 42})
+//
+// Built-in events are detected by the [Detector] system.
+package events
+
+// Event types emitted by the [Detector].
+const (
+	Extinction       = "Extinction"       // The colony died out.
+	HoneyDepletion   = "HoneyDepletion"   // Honey stores are depleted.
+	PollenDepletion  = "PollenDepletion"  // Pollen stores are depleted.
+	BroodCareFailure = "BroodCareFailure" // Brood exceeds the nursing capacity of the colony.
+	Swarming         = "Swarming"         // The number of adult bees exceeds the swarming threshold.
+	PatchDepletion   = "PatchDepletion"   // Nectar or pollen of a flower patch is depleted.
+)
+
+// Event is a discrete model event.
+type Event struct {
+	Run     int            // Index of the run.
+	Tick    int64          // Tick of the event.
+	Type    string         // Type of the event.
+	Payload map[string]any `json:",omitempty"` // Additional data of the event. Optional.
+}
+
+// Log is a resource for emitting events.
+type Log struct {
+	run  int
+	emit func(e Event)
+}
+
+// NewLog creates a new event log for the run with the given index.
+// Function emit is called for each emitted event. It may be nil to discard all events.
+func NewLog(run int, emit func(e Event)) *Log {
+	return &Log{run: run, emit: emit}
+}
+
+// Emit an event.
+func (l *Log) Emit(tick int64, tp string, payload map[string]any) {
+	if l.emit == nil {
+		return
+	}
+	l.emit(Event{Run: l.run, Tick: tick, Type: tp, Payload: payload})
+}
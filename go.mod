@@ -3,6 +3,7 @@ module github.com/mlange-42/beecs-cli
 go 1.24.0
 
 require (
+	git.sr.ht/~sbinet/gg v0.6.0
 	github.com/gopxl/pixel/v2 v2.1.0
 	github.com/mlange-42/ark v0.4.0
 	github.com/mlange-42/ark-pixel v0.1.2
@@ -10,13 +11,14 @@ require (
 	github.com/mlange-42/beecs v0.5.1-0.20250324214504-8d594e34874c
 	github.com/spf13/cobra v1.9.1
 	github.com/spf13/pflag v1.0.6
+	golang.org/x/image v0.25.0
+	gonum.org/v1/plot v0.15.2
 )
 
 require (
 	codeberg.org/go-fonts/liberation v0.4.1 // indirect
 	codeberg.org/go-latex/latex v0.0.1 // indirect
 	codeberg.org/go-pdf/fpdf v0.10.0 // indirect
-	git.sr.ht/~sbinet/gg v0.6.0 // indirect
 	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
 	github.com/campoy/embedmd v1.0.0 // indirect
 	github.com/go-gl/gl v0.0.0-20231021071112-07e5d0ea2e71 // indirect
@@ -31,8 +33,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20250305212735-054e65f0b394 // indirect
-	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	gonum.org/v1/gonum v0.15.1 // indirect
-	gonum.org/v1/plot v0.15.2 // indirect
 )
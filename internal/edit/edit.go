@@ -0,0 +1,139 @@
+// Package edit provides live editing of model parameters from the terminal.
+package edit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/model"
+)
+
+// Session reads edit commands like 'params.Foragers.FlightVelocity=6.5' from an input,
+// and logs applied changes to a CSV file. A session is shared by all runs.
+type Session struct {
+	commands chan string
+	file     *os.File
+	log      *csv.Writer
+}
+
+// NewSession creates a new session reading commands from the given input, and logging to the given file.
+func NewSession(in io.Reader, logFile string) (*Session, error) {
+	file, err := os.Create(logFile)
+	if err != nil {
+		return nil, err
+	}
+	s := Session{
+		commands: make(chan string, 64),
+		file:     file,
+		log:      csv.NewWriter(file),
+	}
+	if err := s.write("Run", "Tick", "Parameter", "Value"); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			s.commands <- scanner.Text()
+		}
+	}()
+	return &s, nil
+}
+
+// Editor creates a UI system applying the session's commands to the run with the given index.
+func (s *Session) Editor(run int) *Editor {
+	return &Editor{session: s, Run: run}
+}
+
+// Close the session's log file.
+func (s *Session) Close() error {
+	return s.file.Close()
+}
+
+// write a record to the log, and flushes it immediately.
+func (s *Session) write(record ...string) error {
+	if err := s.log.Write(record); err != nil {
+		return err
+	}
+	s.log.Flush()
+	return s.log.Error()
+}
+
+// Editor is a UI system that applies edit commands using [model.SetParameter], while the app is paused.
+type Editor struct {
+	Run     int // Index of the run.
+	session *Session
+	systems *app.Systems
+	tick    ecs.Resource[resource.Tick]
+}
+
+// InitializeUI the system
+func (e *Editor) InitializeUI(w *ecs.World) {
+	e.systems = ecs.GetResource[app.Systems](w)
+	e.tick = ecs.NewResource[resource.Tick](w)
+	fmt.Println("Pause the simulation and enter parameter edits like 'params.Foragers.FlightVelocity=6.5'")
+}
+
+// UpdateUI the system
+func (e *Editor) UpdateUI(w *ecs.World) {
+	for {
+		select {
+		case cmd := <-e.session.commands:
+			e.apply(w, cmd)
+		default:
+			return
+		}
+	}
+}
+
+func (e *Editor) apply(w *ecs.World, cmd string) {
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" {
+		return
+	}
+	parts := strings.SplitN(cmd, "=", 2)
+	if len(parts) != 2 {
+		fmt.Printf("Invalid edit '%s', expected syntax 'parameter=value'\n", cmd)
+		return
+	}
+	par, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	if !e.systems.Paused {
+		fmt.Printf("Edit '%s' rejected: pause the simulation first\n", cmd)
+		return
+	}
+	if err := model.SetParameter(w, par, value); err != nil {
+		fmt.Printf("Edit '%s' failed: %s\n", cmd, err.Error())
+		return
+	}
+
+	tick := e.tick.Get().Tick
+	if err := e.session.write(strconv.Itoa(e.Run), strconv.FormatInt(tick, 10), par, value); err != nil {
+		fmt.Printf("WARNING: can't log edit: %s\n", err.Error())
+	}
+	fmt.Printf("Run %d, tick %d: set %s = %s\n", e.Run, tick, par, value)
+}
+
+// PostUpdateUI the system
+func (e *Editor) PostUpdateUI(w *ecs.World) {}
+
+// FinalizeUI the system
+func (e *Editor) FinalizeUI(w *ecs.World) {}
+
+// Initialize the system
+func (e *Editor) Initialize(w *ecs.World) {}
+
+// Update the system
+func (e *Editor) Update(w *ecs.World) {}
+
+// Finalize the system
+func (e *Editor) Finalize(w *ecs.World) {}
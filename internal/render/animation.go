@@ -0,0 +1,337 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+const defaultDelay = 100
+
+// Animation is a system that renders a [Drawer] to an animated GIF or APNG file.
+// The format is determined by the file extension, .gif or .png.
+//
+// Frames of GIF animations are kept in memory until the end of the run,
+// while APNG frames are written immediately.
+// Errors terminate the run, and are available from Err afterwards.
+type Animation struct {
+	failure
+	Drawer   Drawer // Drawer to render.
+	File     string // Output file.
+	Interval int    // Interval between frames, in ticks. Default: 1.
+	Width    int    // Image width in pixels. Default: 800.
+	Height   int    // Image height in pixels. Default: 600.
+	Delay    int    // Display time per frame, in milliseconds. Default: 100.
+	encoder  animationEncoder
+	step     int64
+}
+
+// Initialize the system
+func (a *Animation) Initialize(w *ecs.World) {
+	if a.Interval <= 0 {
+		a.Interval = 1
+	}
+	if a.Width <= 0 {
+		a.Width = defaultWidth
+	}
+	if a.Height <= 0 {
+		a.Height = defaultHeight
+	}
+	if a.Delay <= 0 {
+		a.Delay = defaultDelay
+	}
+	a.err = nil
+	a.encoder = nil
+	if dir := path.Dir(a.File); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			a.fail(w, err)
+			return
+		}
+	}
+	var err error
+	a.encoder, err = newAnimationEncoder(a.File, a.Delay)
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	a.Drawer.Initialize(w)
+	a.step = 0
+}
+
+// Update the system
+func (a *Animation) Update(w *ecs.World) {
+	if a.err != nil {
+		return
+	}
+	a.Drawer.Update(w)
+
+	if a.step%int64(a.Interval) == 0 {
+		img, err := a.Drawer.Render(w, a.Width, a.Height)
+		if err != nil {
+			a.fail(w, err)
+			return
+		}
+		if err := a.encoder.Add(img); err != nil {
+			a.fail(w, err)
+			return
+		}
+	}
+
+	a.step++
+}
+
+// Finalize the system
+func (a *Animation) Finalize(w *ecs.World) {
+	if a.encoder == nil {
+		return
+	}
+	if err := a.encoder.Close(); err != nil {
+		a.fail(w, err)
+	}
+}
+
+// CheckAnimationFile checks whether the extension of a file is supported for animations.
+func CheckAnimationFile(file string) error {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".gif", ".png":
+		return nil
+	default:
+		return fmt.Errorf("unsupported animation file '%s'; use extension .gif or .png", file)
+	}
+}
+
+type animationEncoder interface {
+	Add(img image.Image) error
+	Close() error
+}
+
+func newAnimationEncoder(file string, delay int) (animationEncoder, error) {
+	if err := CheckAnimationFile(file); err != nil {
+		return nil, err
+	}
+	if strings.ToLower(path.Ext(file)) == ".gif" {
+		return &gifEncoder{file: file, delay: (delay + 5) / 10}, nil
+	}
+	return &apngEncoder{file: file, delay: uint16(min(delay, 0xffff))}, nil
+}
+
+// gifEncoder collects frames and writes an animated GIF on close.
+type gifEncoder struct {
+	file  string
+	delay int // In 1/100 s.
+	anim  gif.GIF
+}
+
+func (e *gifEncoder) Add(img image.Image) error {
+	bounds := img.Bounds()
+	frame := image.NewPaletted(bounds, palette.Plan9)
+	draw.Draw(frame, bounds, img, bounds.Min, draw.Src)
+
+	e.anim.Image = append(e.anim.Image, frame)
+	e.anim.Delay = append(e.anim.Delay, e.delay)
+	return nil
+}
+
+func (e *gifEncoder) Close() error {
+	if len(e.anim.Image) == 0 {
+		return nil
+	}
+	f, err := os.Create(e.file)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &e.anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// apngEncoder writes frames to an animated PNG file immediately.
+//
+// Frames are encoded as regular PNG images, and their image data chunks
+// are re-packaged as APNG frames. The number of frames is written on close.
+type apngEncoder struct {
+	file     string
+	delay    uint16 // In milliseconds.
+	f        *os.File
+	ihdr     []byte
+	actlPos  int64
+	frames   uint32
+	sequence uint32
+}
+
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+func (e *apngEncoder) Add(img image.Image) error {
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	chunks, err := readPngChunks(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var ihdr []byte
+	for _, c := range chunks {
+		if c.Type == "IHDR" {
+			ihdr = c.Data
+			break
+		}
+	}
+	if ihdr == nil {
+		return fmt.Errorf("missing IHDR chunk in encoded frame")
+	}
+
+	if e.f == nil {
+		if err := e.start(ihdr, chunks); err != nil {
+			return err
+		}
+	} else if !bytes.Equal(ihdr, e.ihdr) {
+		return fmt.Errorf("frame %d of animation '%s' differs in size or color type", e.frames, e.file)
+	}
+
+	bounds := img.Bounds()
+	if err := e.writeChunk("fcTL", e.frameControl(bounds.Dx(), bounds.Dy())); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if c.Type != "IDAT" {
+			continue
+		}
+		if e.frames == 0 {
+			err = e.writeChunk("IDAT", c.Data)
+		} else {
+			data := binary.BigEndian.AppendUint32(nil, e.sequence)
+			e.sequence++
+			err = e.writeChunk("fdAT", append(data, c.Data...))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	e.frames++
+	return nil
+}
+
+// start creates the file and writes the signature, the header, and all ancillary chunks of the first frame.
+func (e *apngEncoder) start(ihdr []byte, chunks []pngChunk) error {
+	var err error
+	e.f, err = os.Create(e.file)
+	if err != nil {
+		return err
+	}
+	e.ihdr = ihdr
+	if _, err := e.f.Write([]byte(pngSignature)); err != nil {
+		return err
+	}
+	if err := e.writeChunk("IHDR", ihdr); err != nil {
+		return err
+	}
+	if e.actlPos, err = e.f.Seek(0, io.SeekCurrent); err != nil {
+		return err
+	}
+	if err := e.writeChunk("acTL", animationControl(0)); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if c.Type == "IHDR" || c.Type == "IDAT" || c.Type == "IEND" {
+			continue
+		}
+		if err := e.writeChunk(c.Type, c.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *apngEncoder) Close() error {
+	if e.f == nil {
+		return nil
+	}
+	if err := e.writeChunk("IEND", nil); err != nil {
+		e.f.Close()
+		return err
+	}
+	if _, err := e.f.Seek(e.actlPos, io.SeekStart); err != nil {
+		e.f.Close()
+		return err
+	}
+	if err := e.writeChunk("acTL", animationControl(e.frames)); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
+
+func (e *apngEncoder) frameControl(width, height int) []byte {
+	data := make([]byte, 0, 26)
+	data = binary.BigEndian.AppendUint32(data, e.sequence)
+	data = binary.BigEndian.AppendUint32(data, uint32(width))
+	data = binary.BigEndian.AppendUint32(data, uint32(height))
+	data = binary.BigEndian.AppendUint32(data, 0) // x offset
+	data = binary.BigEndian.AppendUint32(data, 0) // y offset
+	data = binary.BigEndian.AppendUint16(data, e.delay)
+	data = binary.BigEndian.AppendUint16(data, 1000)
+	data = append(data, 0, 0) // dispose op none, blend op source
+	e.sequence++
+	return data
+}
+
+func (e *apngEncoder) writeChunk(tp string, data []byte) error {
+	buf := make([]byte, 0, len(data)+12)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, tp...)
+	buf = append(buf, data...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[4:]))
+	_, err := e.f.Write(buf)
+	return err
+}
+
+// animationControl creates the data of an acTL chunk, for infinite looping.
+func animationControl(frames uint32) []byte {
+	data := binary.BigEndian.AppendUint32(nil, frames)
+	return binary.BigEndian.AppendUint32(data, 0)
+}
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+func readPngChunks(b []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(b, []byte(pngSignature)) {
+		return nil, fmt.Errorf("invalid PNG signature")
+	}
+	b = b[len(pngSignature):]
+
+	chunks := []pngChunk{}
+	for len(b) > 0 {
+		if len(b) < 12 {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(b[:4]))
+		if len(b) < length+12 {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		chunks = append(chunks, pngChunk{
+			Type: string(b[4:8]),
+			Data: b[8 : 8+length],
+		})
+		b = b[length+12:]
+	}
+	return chunks, nil
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApngEncoder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "anim.png")
+	enc, err := newAnimationEncoder(file, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	for _, c := range colors {
+		img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+		if err := enc.Add(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readPngChunks(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunk order: IHDR, acTL, ancillary chunks, then fcTL before the image data of each frame, IEND last.
+	if chunks[0].Type != "IHDR" || chunks[1].Type != "acTL" || chunks[len(chunks)-1].Type != "IEND" {
+		t.Fatalf("unexpected chunk order %v", chunkTypes(chunks))
+	}
+	if frames := binary.BigEndian.Uint32(chunks[1].Data); frames != uint32(len(colors)) {
+		t.Errorf("expected %d frames in acTL, got %d", len(colors), frames)
+	}
+
+	frames := 0
+	sequence := uint32(0)
+	last := ""
+	for _, c := range chunks[2 : len(chunks)-1] {
+		switch c.Type {
+		case "fcTL":
+			if seq := binary.BigEndian.Uint32(c.Data); seq != sequence {
+				t.Errorf("expected sequence number %d for fcTL, got %d", sequence, seq)
+			}
+			if delay := binary.BigEndian.Uint16(c.Data[20:]); delay != 250 {
+				t.Errorf("expected delay 250, got %d", delay)
+			}
+			sequence++
+			frames++
+		case "IDAT":
+			if frames != 1 || (last != "fcTL" && last != "IDAT") {
+				t.Errorf("IDAT must follow the first fcTL, got chunks %v", chunkTypes(chunks))
+			}
+		case "fdAT":
+			if frames < 2 || (last != "fcTL" && last != "fdAT") {
+				t.Errorf("fdAT must follow the fcTL of a later frame, got chunks %v", chunkTypes(chunks))
+			}
+			if seq := binary.BigEndian.Uint32(c.Data); seq != sequence {
+				t.Errorf("expected sequence number %d for fdAT, got %d", sequence, seq)
+			}
+			sequence++
+		default:
+			if frames > 0 {
+				t.Errorf("unexpected chunk %s after the first frame", c.Type)
+			}
+		}
+		last = c.Type
+	}
+	if frames != len(colors) {
+		t.Errorf("expected %d fcTL chunks, got %d", len(colors), frames)
+	}
+
+	// The first frame is the default image, for viewers without APNG support.
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := img.At(1, 1).RGBA(); r>>8 != 255 || g != 0 || b != 0 {
+		t.Errorf("expected the first frame to be red, got %v", img.At(1, 1))
+	}
+}
+
+func TestReadPngChunksErrors(t *testing.T) {
+	if _, err := readPngChunks([]byte("GIF89a")); err == nil {
+		t.Error("expected error for invalid signature")
+	}
+	truncated := append([]byte(pngSignature), 0, 0, 0, 13, 'I', 'H', 'D', 'R')
+	if _, err := readPngChunks(truncated); err == nil {
+		t.Error("expected error for truncated chunk")
+	}
+}
+
+func chunkTypes(chunks []pngChunk) []string {
+	types := make([]string, len(chunks))
+	for i, c := range chunks {
+		types[i] = c.Type
+	}
+	return types
+}
@@ -0,0 +1,175 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	pixelplot "github.com/mlange-42/ark-pixel/plot"
+	"github.com/mlange-42/ark-tools/observer"
+	"github.com/mlange-42/ark/ecs"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// Histogram renders histograms of a column of a row observer across the completed runs of an experiment,
+// with one histogram per parameter set. Uses the value of the last update of each run.
+// If no parameter set has more than one completed run, like for experiments with a single
+// run per set, all runs are combined in a single histogram.
+//
+// The value of the current run is shown as a vertical line.
+type Histogram struct {
+	Observer       observer.Row     // Observer providing a data row per update.
+	Column         string           // Column to show, by name.
+	Bins           int              // Number of bins. Optional, default 10.
+	UpdateInterval int              // Interval for getting data from the the observer, in model ticks. Optional.
+	Labels         pixelplot.Labels // Labels for plot and axes. Optional.
+	History        *History         // History of completed runs. Optional, shows only the current run if nil.
+	Plot           int              // Index of the plot in the history.
+	index          int
+	value          float64
+	step           int64
+	err            error
+}
+
+// Initialize the drawer.
+func (h *Histogram) Initialize(w *ecs.World) {
+	h.Observer.Initialize(w)
+	if h.UpdateInterval <= 0 {
+		h.UpdateInterval = 1
+	}
+	if h.Bins <= 0 {
+		h.Bins = 10
+	}
+	h.index = -1
+	h.err = nil
+	if h.Column == "" {
+		h.err = fmt.Errorf("histogram requires a column")
+	} else {
+		var indices []int
+		if _, indices, h.err = selectColumns(h.Observer.Header(), []string{h.Column}); h.err == nil {
+			h.index = indices[0]
+		}
+	}
+	if e, ok := h.Observer.(interface{ Err() error }); ok && e.Err() != nil {
+		h.err = e.Err()
+		h.index = -1
+	}
+	h.value = math.NaN()
+	h.step = 0
+
+	if h.History != nil {
+		h.History.TrackValue(h.Plot, func() float64 { return h.value })
+	}
+}
+
+// Update the drawer.
+func (h *Histogram) Update(w *ecs.World) {
+	h.Observer.Update(w)
+	if h.index >= 0 && h.step%int64(h.UpdateInterval) == 0 {
+		h.value = h.Observer.Values(w)[h.index]
+	}
+	h.step++
+}
+
+// Render the drawer to an image.
+// Returns an error if the column was not found on initialization.
+func (h *Histogram) Render(w *ecs.World, width, height int) (image.Image, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	var values []SetValue
+	if h.History != nil {
+		values = h.History.Values(h.Plot)
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range append(values, SetValue{Value: h.value}) {
+		if !math.IsNaN(v.Value) && !math.IsInf(v.Value, 0) {
+			lo, hi = math.Min(lo, v.Value), math.Max(hi, v.Value)
+		}
+	}
+	p := newPlot(&h.Labels)
+	if lo > hi {
+		return drawPlot(p, width, height), nil
+	}
+	if lo == hi {
+		lo, hi = lo-0.5, hi+0.5
+	}
+	binWidth := (hi - lo) / float64(h.Bins)
+
+	values = combineSets(values)
+
+	sets := []string{}
+	counts := map[string][]float64{}
+	for _, v := range values {
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			continue
+		}
+		c, ok := counts[v.Set]
+		if !ok {
+			c = make([]float64, h.Bins)
+			counts[v.Set] = c
+			sets = append(sets, v.Set)
+		}
+		bin := min(int((v.Value-lo)/binWidth), h.Bins-1)
+		c[bin]++
+	}
+
+	maxCount := 1.0
+	for i, set := range sets {
+		c := counts[set]
+		xys := plotter.XYs{{X: lo, Y: 0}}
+		for bin, n := range c {
+			x := lo + float64(bin)*binWidth
+			xys = append(xys, plotter.XY{X: x, Y: n}, plotter.XY{X: x + binWidth, Y: n})
+			maxCount = math.Max(maxCount, n)
+		}
+		xys = append(xys, plotter.XY{X: hi, Y: 0})
+
+		label := set
+		if label == "" {
+			label = "completed runs"
+		}
+		if err := addLine(p, xys, label, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if !math.IsNaN(h.value) && !math.IsInf(h.value, 0) {
+		line, err := plotter.NewLine(plotter.XYs{{X: h.value, Y: 0}, {X: h.value, Y: maxCount}})
+		if err != nil {
+			return nil, err
+		}
+		line.LineStyle.Color = color.Gray{Y: 64}
+		line.LineStyle.Width = vg.Points(1)
+		line.LineStyle.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		p.Add(line)
+		p.Legend.Add("current run", line)
+	}
+	p.X.Min, p.X.Max = lo, hi
+	p.Y.Min, p.Y.Max = 0, maxCount
+
+	return drawPlot(p, width, height), nil
+}
+
+// combineSets removes the set labels of values if no set has more than one value,
+// so that they are shown as a single histogram instead of one single-bar histogram per set.
+func combineSets(values []SetValue) []SetValue {
+	counts := map[string]int{}
+	for _, v := range values {
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			continue
+		}
+		counts[v.Set]++
+		if counts[v.Set] > 1 {
+			return values
+		}
+	}
+	combined := make([]SetValue, len(values))
+	for i, v := range values {
+		combined[i] = SetValue{Value: v.Value}
+	}
+	return combined
+}
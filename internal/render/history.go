@@ -0,0 +1,84 @@
+package render
+
+import (
+	"sync"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// History keeps data of completed runs, for live plots across the runs of an experiment.
+//
+// Drawers register their data of the current run via [History.TrackLines] and [History.TrackValue].
+// At the end of each run, [History.Finish] stores the tracked data and removes all trackers.
+// History is safe for concurrent use.
+type History struct {
+	mu          sync.Mutex
+	lines       map[int][][]plotter.XYs
+	values      map[int][]SetValue
+	trackLines  map[int]func() []plotter.XYs
+	trackValues map[int]func() float64
+}
+
+// SetValue is the final value of a completed run, with the label of its parameter set.
+type SetValue struct {
+	Set   string
+	Value float64
+}
+
+// NewHistory creates a new, empty history.
+func NewHistory() *History {
+	return &History{
+		lines:       map[int][][]plotter.XYs{},
+		values:      map[int][]SetValue{},
+		trackLines:  map[int]func() []plotter.XYs{},
+		trackValues: map[int]func() float64{},
+	}
+}
+
+// TrackLines registers a function providing the lines of a time series plot in the current run.
+func (h *History) TrackLines(plot int, fn func() []plotter.XYs) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.trackLines[plot] = fn
+}
+
+// TrackValue registers a function providing the current value of a histogram plot in the current run.
+func (h *History) TrackValue(plot int, fn func() float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.trackValues[plot] = fn
+}
+
+// Finish stores the tracked data of the current run, under the given parameter set label.
+func (h *History) Finish(set string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for plot, fn := range h.trackLines {
+		lines := fn()
+		cp := make([]plotter.XYs, len(lines))
+		for i, l := range lines {
+			cp[i] = append(plotter.XYs{}, l...)
+		}
+		h.lines[plot] = append(h.lines[plot], cp)
+	}
+	for plot, fn := range h.trackValues {
+		h.values[plot] = append(h.values[plot], SetValue{Set: set, Value: fn()})
+	}
+	clear(h.trackLines)
+	clear(h.trackValues)
+}
+
+// Lines returns the lines of a time series plot for all completed runs.
+func (h *History) Lines(plot int) [][]plotter.XYs {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([][]plotter.XYs{}, h.lines[plot]...)
+}
+
+// Values returns the final values of a histogram plot for all completed runs.
+func (h *History) Values(plot int) []SetValue {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]SetValue{}, h.values[plot]...)
+}
@@ -0,0 +1,270 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	pixelplot "github.com/mlange-42/ark-pixel/plot"
+	"github.com/mlange-42/ark-tools/observer"
+	"github.com/mlange-42/ark/ecs"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+// TimeSeries renders a time series plot of the columns of a row observer,
+// like [pixelplot.TimeSeries].
+//
+// With a [History], completed runs are drawn as faint lines behind the current run.
+type TimeSeries struct {
+	Observer       observer.Row     // Observer providing a data row per update.
+	Columns        []string         // Columns to show, by name. Optional, default all.
+	UpdateInterval int              // Interval for getting data from the the observer, in model ticks. Optional.
+	Labels         pixelplot.Labels // Labels for plot and axes. Optional.
+	MaxRows        int              // Maximum number of rows to keep. Zero means unlimited. Optional.
+	History        *History         // History for overlays of completed runs. Optional.
+	Plot           int              // Index of the plot in the history.
+	headers        []string
+	indices        []int
+	series         []plotter.XYs
+	step           int64
+	err            error
+}
+
+// Initialize the drawer.
+func (t *TimeSeries) Initialize(w *ecs.World) {
+	t.Observer.Initialize(w)
+	if t.UpdateInterval <= 0 {
+		t.UpdateInterval = 1
+	}
+
+	t.headers, t.indices, t.err = selectColumns(t.Observer.Header(), t.Columns)
+	if e, ok := t.Observer.(interface{ Err() error }); ok && e.Err() != nil {
+		t.err = e.Err()
+	}
+	t.series = make([]plotter.XYs, len(t.indices))
+	t.step = 0
+
+	if t.History != nil {
+		t.History.TrackLines(t.Plot, func() []plotter.XYs { return t.series })
+	}
+}
+
+// Update the drawer.
+func (t *TimeSeries) Update(w *ecs.World) {
+	t.Observer.Update(w)
+	if t.step%int64(t.UpdateInterval) == 0 {
+		values := t.Observer.Values(w)
+		for i, idx := range t.indices {
+			t.series[i] = append(t.series[i], plotter.XY{X: float64(t.step), Y: values[idx]})
+			if t.MaxRows > 0 && len(t.series[i]) > t.MaxRows {
+				t.series[i] = t.series[i][1:]
+			}
+		}
+	}
+	t.step++
+}
+
+// Render the drawer to an image.
+// Returns an error if columns were not found on initialization.
+func (t *TimeSeries) Render(w *ecs.World, width, height int) (image.Image, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	p := newPlot(&t.Labels)
+	if t.History != nil {
+		for _, run := range t.History.Lines(t.Plot) {
+			for i, s := range run {
+				if err := addOverlayLine(p, s, i); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for i, s := range t.series {
+		if err := addLine(p, s, t.headers[i], i); err != nil {
+			return nil, err
+		}
+	}
+	return drawPlot(p, width, height), nil
+}
+
+// Lines renders a line plot of the columns of a table observer,
+// like [pixelplot.Lines].
+type Lines struct {
+	Observer observer.Table   // Observer providing a data table per update.
+	X        string           // X column name. Optional, default row index.
+	Y        []string         // Y column names. Optional, default all but X.
+	XLim     [2]float64       // X axis limits. Optional, default auto.
+	YLim     [2]float64       // Y axis limits. Optional, default auto.
+	Labels   pixelplot.Labels // Labels for plot and axes. Optional.
+	xIndex   int
+	headers  []string
+	indices  []int
+	err      error
+}
+
+// Initialize the drawer.
+func (l *Lines) Initialize(w *ecs.World) {
+	l.Observer.Initialize(w)
+
+	header := l.Observer.Header()
+	l.xIndex = -1
+	l.indices = nil
+	if l.X != "" {
+		_, idx, err := selectColumns(header, []string{l.X})
+		if err != nil {
+			l.err = err
+			return
+		}
+		l.xIndex = idx[0]
+	}
+
+	columns := l.Y
+	if len(columns) == 0 {
+		for i, h := range header {
+			if i != l.xIndex {
+				columns = append(columns, h)
+			}
+		}
+	}
+	l.headers, l.indices, l.err = selectColumns(header, columns)
+}
+
+// Update the drawer.
+func (l *Lines) Update(w *ecs.World) {
+	l.Observer.Update(w)
+}
+
+// Render the drawer to an image.
+// Returns an error if columns were not found on initialization.
+func (l *Lines) Render(w *ecs.World, width, height int) (image.Image, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	data := l.Observer.Values(w)
+
+	p := newPlot(&l.Labels)
+	for i, idx := range l.indices {
+		xys := make(plotter.XYs, len(data))
+		for r, row := range data {
+			x := float64(r)
+			if l.xIndex >= 0 {
+				x = row[l.xIndex]
+			}
+			xys[r] = plotter.XY{X: x, Y: row[idx]}
+		}
+		if err := addLine(p, xys, l.headers[i], i); err != nil {
+			return nil, err
+		}
+	}
+	if l.XLim[0] != 0 || l.XLim[1] != 0 {
+		p.X.Min, p.X.Max = l.XLim[0], l.XLim[1]
+	}
+	if l.YLim[0] != 0 || l.YLim[1] != 0 {
+		p.Y.Min, p.Y.Max = l.YLim[0], l.YLim[1]
+	}
+	return drawPlot(p, width, height), nil
+}
+
+// selectColumns finds the given columns in a header.
+// Returns all columns if columns is empty.
+// Returns an error if a column is not found.
+func selectColumns(header []string, columns []string) ([]string, []int, error) {
+	if len(columns) == 0 {
+		indices := make([]int, len(header))
+		for i := range header {
+			indices[i] = i
+		}
+		return header, indices, nil
+	}
+	indices := make([]int, len(columns))
+	for i, col := range columns {
+		indices[i] = -1
+		for j, h := range header {
+			if h == col {
+				indices[i] = j
+				break
+			}
+		}
+		if indices[i] < 0 {
+			return nil, nil, fmt.Errorf("column '%s' not found in observer header %v", col, header)
+		}
+	}
+	return columns, indices, nil
+}
+
+func newPlot(labels *pixelplot.Labels) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = labels.Title
+	p.X.Label.Text = labels.X
+	p.Y.Label.Text = labels.Y
+	p.Legend.Top = true
+	return p
+}
+
+// addLine adds a line to a plot, skipping NaN and infinite values.
+// Adds no legend entry if the label is empty.
+func addLine(p *plot.Plot, xys plotter.XYs, label string, index int) error {
+	clean := finiteXYs(xys)
+	if len(clean) == 0 {
+		return nil
+	}
+	line, err := plotter.NewLine(clean)
+	if err != nil {
+		return err
+	}
+	line.LineStyle.Color = plotutil.Color(index)
+	line.LineStyle.Width = vg.Points(1)
+	p.Add(line)
+	if label != "" {
+		p.Legend.Add(label, line)
+	}
+	return nil
+}
+
+// overlayAlpha is the opacity of lines of completed runs.
+const overlayAlpha = 64
+
+// addOverlayLine adds a faint line of a completed run to a plot, without legend entry.
+func addOverlayLine(p *plot.Plot, xys plotter.XYs, index int) error {
+	clean := finiteXYs(xys)
+	if len(clean) == 0 {
+		return nil
+	}
+	line, err := plotter.NewLine(clean)
+	if err != nil {
+		return err
+	}
+	r, g, b, _ := plotutil.Color(index).RGBA()
+	line.LineStyle.Color = color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), overlayAlpha}
+	line.LineStyle.Width = vg.Points(1)
+	p.Add(line)
+	return nil
+}
+
+// finiteXYs returns the points with finite Y values.
+func finiteXYs(xys plotter.XYs) plotter.XYs {
+	clean := make(plotter.XYs, 0, len(xys))
+	for _, xy := range xys {
+		if !math.IsNaN(xy.Y) && !math.IsInf(xy.Y, 0) {
+			clean = append(clean, xy)
+		}
+	}
+	return clean
+}
+
+// drawPlot draws a plot to an image with the given size in pixels.
+func drawPlot(p *plot.Plot, width, height int) image.Image {
+	c := vgimg.NewWith(
+		vgimg.UseWH(vg.Length(width), vg.Length(height)),
+		vgimg.UseDPI(72),
+	)
+	p.Draw(draw.New(c))
+	return c.Image()
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSelectColumns(t *testing.T) {
+	header := []string{"A", "B", "C"}
+	tests := []struct {
+		name    string
+		columns []string
+		names   []string
+		indices []int
+		err     bool
+	}{
+		{"all", nil, header, []int{0, 1, 2}, false},
+		{"select", []string{"C", "A"}, []string{"C", "A"}, []int{2, 0}, false},
+		{"missing", []string{"A", "D"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, indices, err := selectColumns(header, tt.columns)
+			if tt.err {
+				if err == nil {
+					t.Error("expected error for missing column")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(names, tt.names) || !slices.Equal(indices, tt.indices) {
+				t.Errorf("expected %v %v, got %v %v", tt.names, tt.indices, names, indices)
+			}
+		})
+	}
+}
+
+func TestCombineSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []SetValue
+		expected []SetValue
+	}{
+		{"empty", nil, []SetValue{}},
+		{"single runs", []SetValue{{"a", 1}, {"b", 2}}, []SetValue{{"", 1}, {"", 2}}},
+		{"repeated runs", []SetValue{{"a", 1}, {"b", 2}, {"a", 3}}, []SetValue{{"a", 1}, {"b", 2}, {"a", 3}}},
+		{"ignores nan", []SetValue{{"a", 1}, {"a", math.NaN()}}, []SetValue{{"", 1}, {"", math.NaN()}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			combined := combineSets(tt.values)
+			if len(combined) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, combined)
+			}
+			for i, v := range combined {
+				e := tt.expected[i]
+				if v.Set != e.Set || (v.Value != e.Value && !(math.IsNaN(v.Value) && math.IsNaN(e.Value))) {
+					t.Errorf("expected %v, got %v", tt.expected, combined)
+				}
+			}
+		})
+	}
+}
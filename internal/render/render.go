@@ -0,0 +1,135 @@
+// Package render provides headless rendering of plots and views to image files.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/view"
+)
+
+const (
+	defaultWidth  = 800
+	defaultHeight = 600
+)
+
+// Drawer is rendered to images by [Frames].
+type Drawer interface {
+	Initialize(w *ecs.World)                                     // Initialize the drawer.
+	Update(w *ecs.World)                                         // Update the drawer.
+	Render(w *ecs.World, width, height int) (image.Image, error) // Render the drawer to an image.
+}
+
+// failure records the first error of a system or drawer, and terminates the run on errors.
+type failure struct {
+	err error
+}
+
+// Err returns the first error during the run, or nil.
+func (f *failure) Err() error {
+	return f.err
+}
+
+func (f *failure) fail(w *ecs.World, err error) {
+	if f.err == nil {
+		f.err = err
+	}
+	ecs.GetResource[resource.Termination](w).Terminate = true
+}
+
+// Frames is a system that renders a [Drawer] to numbered PNG files.
+// Errors terminate the run, and are available from Err afterwards.
+type Frames struct {
+	failure
+	Drawer   Drawer // Drawer to render.
+	Dir      string // Output directory.
+	Interval int    // Interval between frames, in ticks. Default: 1.
+	Width    int    // Image width in pixels. Default: 800.
+	Height   int    // Image height in pixels. Default: 600.
+	step     int64
+	frame    int
+}
+
+// Initialize the system
+func (f *Frames) Initialize(w *ecs.World) {
+	if f.Interval <= 0 {
+		f.Interval = 1
+	}
+	if f.Width <= 0 {
+		f.Width = defaultWidth
+	}
+	if f.Height <= 0 {
+		f.Height = defaultHeight
+	}
+	f.err = nil
+	if err := os.MkdirAll(f.Dir, os.ModePerm); err != nil {
+		f.fail(w, err)
+		return
+	}
+	f.Drawer.Initialize(w)
+	f.step = 0
+	f.frame = 0
+}
+
+// Update the system
+func (f *Frames) Update(w *ecs.World) {
+	if f.err != nil {
+		return
+	}
+	f.Drawer.Update(w)
+
+	if f.step%int64(f.Interval) == 0 {
+		img, err := f.Drawer.Render(w, f.Width, f.Height)
+		if err != nil {
+			f.fail(w, err)
+			return
+		}
+		if err := writePNG(path.Join(f.Dir, fmt.Sprintf("frame-%06d.png", f.frame)), img); err != nil {
+			f.fail(w, err)
+			return
+		}
+		f.frame++
+	}
+
+	f.step++
+}
+
+// Finalize the system
+func (f *Frames) Finalize(w *ecs.World) {}
+
+// View renders a [view.ImageDrawer].
+type View struct {
+	Drawer view.ImageDrawer
+}
+
+// Initialize the drawer.
+func (v *View) Initialize(w *ecs.World) {
+	v.Drawer.InitializeImage(w)
+}
+
+// Update the drawer.
+func (v *View) Update(w *ecs.World) {
+	v.Drawer.Update(w)
+}
+
+// Render the drawer to an image.
+func (v *View) Render(w *ecs.World, width, height int) (image.Image, error) {
+	return v.Drawer.DrawImage(w, width, height), nil
+}
+
+func writePNG(file string, img image.Image) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
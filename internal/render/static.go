@@ -0,0 +1,129 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+
+	pixelplot "github.com/mlange-42/ark-pixel/plot"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// Series is a series of X and Y values.
+type Series struct {
+	Label string // Legend label. Empty for no legend entry.
+	Color int    // Color index.
+	X, Y  []float64
+}
+
+// Band is a series with a central line and an envelope.
+type Band struct {
+	Label          string // Legend label. Empty for no legend entry.
+	Color          int    // Color index.
+	X              []float64
+	Low, Mid, High []float64
+}
+
+// StaticPlot is a plot for output to image and vector graphics files.
+type StaticPlot struct {
+	Labels pixelplot.Labels // Labels for plot and axes.
+	Lines  []Series         // Line series.
+	Bands  []Band           // Bands with central line.
+	Points []Series         // Scatter series.
+}
+
+// Save the plot to a file, with width and height in centimeters.
+// The format is determined by the file extension, e.g. .png, .svg or .pdf.
+func (s *StaticPlot) Save(file string, width, height float64) error {
+	p, err := s.build()
+	if err != nil {
+		return err
+	}
+	return p.Save(vg.Length(width)*vg.Centimeter, vg.Length(height)*vg.Centimeter, file)
+}
+
+// Encode the plot in the given format, e.g. "png" or "svg", with width and height in centimeters.
+func (s *StaticPlot) Encode(format string, width, height float64) ([]byte, error) {
+	p, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	wt, err := p.WriterTo(vg.Length(width)*vg.Centimeter, vg.Length(height)*vg.Centimeter, format)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.Buffer{}
+	if _, err := wt.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *StaticPlot) build() (*plot.Plot, error) {
+	p := newPlot(&s.Labels)
+	p.Legend.Left = true
+
+	for _, b := range s.Bands {
+		if err := addBand(p, &b); err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range s.Lines {
+		if err := addLine(p, toXYs(l.X, l.Y), l.Label, l.Color); err != nil {
+			return nil, err
+		}
+	}
+	for _, pt := range s.Points {
+		if err := addPoints(p, &pt); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func addBand(p *plot.Plot, b *Band) error {
+	if len(b.X) > 0 {
+		outline := make(plotter.XYs, 0, 2*len(b.X))
+		for i := range b.X {
+			outline = append(outline, plotter.XY{X: b.X[i], Y: b.Low[i]})
+		}
+		for i := len(b.X) - 1; i >= 0; i-- {
+			outline = append(outline, plotter.XY{X: b.X[i], Y: b.High[i]})
+		}
+		poly, err := plotter.NewPolygon(outline)
+		if err != nil {
+			return err
+		}
+		r, g, bl, _ := plotutil.Color(b.Color).RGBA()
+		poly.Color = color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(bl >> 8), A: 64}
+		poly.LineStyle.Width = 0
+		p.Add(poly)
+	}
+	return addLine(p, toXYs(b.X, b.Mid), b.Label, b.Color)
+}
+
+func addPoints(p *plot.Plot, s *Series) error {
+	sc, err := plotter.NewScatter(toXYs(s.X, s.Y))
+	if err != nil {
+		return err
+	}
+	sc.GlyphStyle.Color = plotutil.Color(s.Color)
+	sc.GlyphStyle.Shape = draw.CircleGlyph{}
+	sc.GlyphStyle.Radius = vg.Points(2.5)
+	p.Add(sc)
+	if s.Label != "" {
+		p.Legend.Add(s.Label, sc)
+	}
+	return nil
+}
+
+func toXYs(x, y []float64) plotter.XYs {
+	xys := make(plotter.XYs, len(x))
+	for i := range x {
+		xys[i] = plotter.XY{X: x[i], Y: y[i]}
+	}
+	return xys
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// WindowDrawer draws a [Drawer] to a window, for live plots not provided by ark-pixel.
+// Implements the ark-pixel window drawer interface.
+// Errors terminate the run, and are available from Err afterwards.
+type WindowDrawer struct {
+	failure
+	Drawer Drawer // Drawer to draw.
+}
+
+// Initialize the drawer.
+func (d *WindowDrawer) Initialize(w *ecs.World, win *opengl.Window) {
+	d.err = nil
+	d.Drawer.Initialize(w)
+}
+
+// Update the drawer.
+func (d *WindowDrawer) Update(w *ecs.World) {
+	d.Drawer.Update(w)
+}
+
+// UpdateInputs handles input events of the previous frame update.
+func (d *WindowDrawer) UpdateInputs(w *ecs.World, win *opengl.Window) {}
+
+// Draw the drawer.
+func (d *WindowDrawer) Draw(w *ecs.World, win *opengl.Window) {
+	if d.err != nil {
+		return
+	}
+	bounds := win.Canvas().Bounds()
+	img, err := d.Drawer.Render(w, int(bounds.W()), int(bounds.H()))
+	if err != nil {
+		d.fail(w, err)
+		return
+	}
+	pic := pixel.PictureDataFromImage(img)
+	pixel.NewSprite(pic, pic.Bounds()).Draw(win, pixel.IM.Moved(bounds.Center()))
+}
@@ -0,0 +1,165 @@
+package run
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/events"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/params"
+)
+
+// Callback runs experiments without UI and file output, and passes all table output to a callback.
+//
+// Runs are performed in parallel if threads is larger than 1. Calls of fn are serialized.
+// Cancelling the context terminates the current runs and skips all remaining runs.
+// If fn returns an error, the experiment is cancelled and the error is returned.
+func Callback(
+	ctx context.Context,
+	p params.Params,
+	exp *experiment.Experiment,
+	observers *util.ObserversDef,
+	systems []app.System,
+	overwrite []experiment.ParameterValue,
+	hooks []util.HookDef,
+	snapshots snapshot.Options,
+	forkDef *util.ForkDef,
+	threads int, rng *rand.Rand,
+	indices []int,
+	fn func(tables *util.Tables) error,
+) error {
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	mu := sync.Mutex{}
+	var fnErr error
+	write := func(tables *util.Tables) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fnErr != nil {
+			return nil
+		}
+		if err := fn(cloneTables(tables)); err != nil {
+			fnErr = err
+			cancelFn()
+		}
+		return nil
+	}
+
+	maxRuns := exp.TotalRuns()
+	seeds := runSeeds(maxRuns, rng)
+	jobs := make(chan job, maxRuns)
+	_ = iterate(maxRuns, indices, func(idx int) error {
+		jobs <- job{Index: idx, Seed: seeds[idx]}
+		return nil
+	})
+	close(jobs)
+
+	opts := runOptions{
+		Observers: observers,
+		Systems:   systems,
+		Overwrite: overwrite,
+		Hooks:     hooks,
+		Snapshots: snapshots,
+		Fork:      forkDef,
+		NoUI:      true,
+	}
+	var runErr error
+	wg := sync.WaitGroup{}
+	for range max(threads, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := app.New()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				err := forEachRun(ctx, p, exp, &opts, m, j.Index, j.Seed,
+					func(run int, fk *fork, opts *runOptions) error {
+						res, err := runModel(ctx, p, exp, opts, fk, m, run, j.Seed, write)
+						if err != nil {
+							return err
+						}
+						_ = write(&res)
+						return nil
+					})
+				if err != nil {
+					mu.Lock()
+					if runErr == nil {
+						runErr = err
+					}
+					mu.Unlock()
+					cancelFn()
+					continue
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if runErr != nil {
+		return runErr
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	return context.Cause(ctx)
+}
+
+// cloneTables copies the table slices, as runs reuse them for the next chunk.
+func cloneTables(tables *util.Tables) *util.Tables {
+	data := make([][][]float64, len(tables.Data))
+	for i, d := range tables.Data {
+		data[i] = append([][]float64{}, d...)
+	}
+	return &util.Tables{
+		Index:   tables.Index,
+		Headers: tables.Headers,
+		Data:    data,
+		Events:  append([]events.Event{}, tables.Events...),
+	}
+}
+
+// cancel is a system that terminates a run when its context is cancelled.
+type cancel struct {
+	Context context.Context
+	term    ecs.Resource[resource.Termination]
+}
+
+// Initialize the system
+func (c *cancel) Initialize(w *ecs.World) {
+	c.term = ecs.NewResource[resource.Termination](w)
+}
+
+// Update the system
+func (c *cancel) Update(w *ecs.World) {
+	if c.Context.Err() != nil {
+		c.term.Get().Terminate = true
+	}
+}
+
+// Finalize the system
+func (c *cancel) Finalize(w *ecs.World) {}
+
+// initializer is a system that calls a function on initialization, after all systems added before.
+type initializer struct {
+	Init func()
+}
+
+// Initialize the system
+func (i *initializer) Initialize(w *ecs.World) {
+	i.Init()
+}
+
+// Update the system
+func (i *initializer) Update(w *ecs.World) {}
+
+// Finalize the system
+func (i *initializer) Finalize(w *ecs.World) {}
@@ -0,0 +1,152 @@
+package run
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/beecs-cli/events"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/params"
+)
+
+// fork identifies a treatment run, forked from the spin-up of a run of the experiment.
+type fork struct {
+	Base      int                // Index of the run of the experiment, for its parameter values.
+	Treatment int                // Index of the treatment.
+	Def       *util.TreatmentDef // Definition of the treatment.
+	SpinUp    *spinUpOutput      // Table rows and events of the spin-up, recorded as part of the treatment.
+}
+
+// spinUpOutput buffers the table rows and events of a spin-up in temporary files,
+// for recording them as part of each treatment.
+type spinUpOutput struct {
+	Tables util.CsvWriter
+	Events *util.EventWriter
+}
+
+// newSpinUpOutput creates temporary files for the given number of tables, including the parameters table.
+func newSpinUpOutput(tables int) (*spinUpOutput, error) {
+	temp, err := util.NewTempCsvWriter(tables, ",")
+	if err != nil {
+		return nil, err
+	}
+	evts, err := util.NewTempEventWriter()
+	if err != nil {
+		temp.Remove()
+		return nil, err
+	}
+	return &spinUpOutput{Tables: temp, Events: evts}, nil
+}
+
+// write a chunk of the spin-up's output.
+func (o *spinUpOutput) write(tables *util.Tables) error {
+	if err := o.Tables.Write(tables); err != nil {
+		return err
+	}
+	return o.Events.Write(tables.Events)
+}
+
+// close the temporary files, before reading them.
+func (o *spinUpOutput) close() error {
+	if err := o.Tables.Close(); err != nil {
+		return err
+	}
+	return o.Events.Close()
+}
+
+// remove the temporary files.
+func (o *spinUpOutput) remove() {
+	o.Tables.Remove()
+	o.Events.Remove()
+}
+
+// replay the spin-up's table rows and events as those of the run with the given index.
+func (o *spinUpOutput) replay(idx int, row func(table int, row []float64), event func(e events.Event)) error {
+	err := o.Tables.Rows(func(table int, r []float64) {
+		r[0] = float64(idx)
+		row(table, r)
+	})
+	if err != nil {
+		return err
+	}
+	return o.Events.Events(func(e events.Event) {
+		e.Run = idx
+		event(e)
+	})
+}
+
+// forEachRun calls fn for the run of the experiment with the given index.
+// If a fork is defined, the run is simulated up to the fork tick first,
+// and fn is called for each treatment, with the options to start from the spin-up's state.
+//
+// The table output and events of the spin-up are buffered in temporary files,
+// and are recorded for each treatment, so that treatments cover all ticks.
+// Hooks of the spin-up receive the index of the run of the experiment,
+// and are not called at AfterFinish, as the run is continued by the treatments.
+func forEachRun(
+	ctx context.Context,
+	p params.Params,
+	exp *experiment.Experiment,
+	opts *runOptions,
+	a *app.App,
+	idx int, rSeed int32,
+	fn func(idx int, fk *fork, opts *runOptions) error,
+) error {
+	forkDef := opts.Fork
+	if forkDef == nil {
+		return fn(idx, nil, opts)
+	}
+	snapshots := opts.Snapshots
+	if snapshots.From != nil && snapshots.From.Tick >= forkDef.Tick {
+		return fmt.Errorf("fork tick %d must be after the tick of the snapshot to start from, %d", forkDef.Tick, snapshots.From.Tick)
+	}
+	if snapshots.File != "" && snapshots.Tick < forkDef.Tick {
+		return fmt.Errorf("snapshot tick %d must not be before the fork tick %d", snapshots.Tick, forkDef.Tick)
+	}
+
+	// The spin-up records tables and events only. Plots, views and animations are not shown.
+	spinUpObservers := util.ObserversDef{
+		CsvSeparator: opts.Observers.CsvSeparator,
+		Tables:       opts.Observers.Tables,
+		StepTables:   opts.Observers.StepTables,
+		EventLog:     opts.Observers.EventLog,
+	}
+	output, err := newSpinUpOutput(len(spinUpObservers.Tables) + len(spinUpObservers.StepTables) + 1)
+	if err != nil {
+		return err
+	}
+	defer output.remove()
+
+	spinUp := snapshot.SpinUp{Tick: forkDef.Tick - 1}
+	spinUpOpts := runOptions{
+		Observers: &spinUpObservers,
+		Systems:   opts.Systems,
+		Overwrite: opts.Overwrite,
+		Hooks:     opts.Hooks,
+		Snapshots: snapshot.Options{From: snapshots.From, SpinUp: &spinUp},
+		NoUI:      true,
+	}
+	_, err = runModel(ctx, p, exp, &spinUpOpts, nil, a, idx, rSeed, output.write)
+	if err != nil {
+		return err
+	}
+	if err := output.close(); err != nil {
+		return err
+	}
+
+	treatmentOpts := *opts
+	treatmentOpts.Snapshots = snapshot.Options{From: spinUp.Snapshot, Random: true, File: snapshots.File, Tick: snapshots.Tick}
+	for i := range forkDef.Treatments {
+		if ctx.Err() != nil {
+			return nil
+		}
+		fk := fork{Base: idx, Treatment: i, Def: &forkDef.Treatments[i], SpinUp: output}
+		if err := fn(idx*forkDef.Forks()+i, &fk, &treatmentOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
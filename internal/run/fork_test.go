@@ -0,0 +1,135 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/events"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/globals"
+)
+
+// depletion is a system for tests that depletes pollen stores at tick 3, and terminates the run at tick 9.
+type depletion struct {
+	tick   ecs.Resource[resource.Tick]
+	term   ecs.Resource[resource.Termination]
+	stores ecs.Resource[globals.Stores]
+}
+
+func (d *depletion) Initialize(w *ecs.World) {
+	ecs.AddResource(w, &globals.Stores{Honey: 1, Pollen: 1})
+	ecs.AddResource(w, &globals.PopulationStats{WorkersInHive: 10, TotalPopulation: 10})
+	d.tick = ecs.NewResource[resource.Tick](w)
+	d.term = ecs.NewResource[resource.Termination](w)
+	d.stores = ecs.NewResource[globals.Stores](w)
+}
+
+func (d *depletion) Update(w *ecs.World) {
+	tick := d.tick.Get().Tick
+	if tick == 3 {
+		d.stores.Get().Pollen = 0
+	}
+	if tick >= 9 {
+		d.term.Get().Terminate = true
+	}
+}
+
+func (d *depletion) Finalize(w *ecs.World) {}
+
+func TestForEachRunOptions(t *testing.T) {
+	forkDef := util.ForkDef{Tick: 365, Treatments: []util.TreatmentDef{{Name: "A"}, {Name: "B"}}}
+	tests := []struct {
+		name      string
+		snapshots snapshot.Options
+		err       string
+	}{
+		{"from after fork", snapshot.Options{From: &snapshot.Snapshot{Tick: 400}}, "fork tick"},
+		{"from at fork", snapshot.Options{From: &snapshot.Snapshot{Tick: 365}}, "fork tick"},
+		{"save before fork", snapshot.Options{File: "snapshot.json", Tick: 100}, "snapshot tick"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := runOptions{Observers: &util.ObserversDef{}, Snapshots: tt.snapshots, Fork: &forkDef}
+			err := forEachRun(context.Background(), nil, nil, &opts, nil, 0, 0,
+				func(idx int, fk *fork, opts *runOptions) error {
+					t.Fatal("unexpected run")
+					return nil
+				})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error about %s, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestForEachRunNoFork(t *testing.T) {
+	snapshots := snapshot.Options{File: "snapshot.json", Tick: 100}
+	opts := runOptions{Observers: &util.ObserversDef{}, Snapshots: snapshots}
+	calls := 0
+	err := forEachRun(context.Background(), nil, nil, &opts, nil, 3, 0,
+		func(idx int, fk *fork, opts *runOptions) error {
+			calls++
+			if idx != 3 || fk != nil || opts.Snapshots.File != snapshots.File {
+				t.Errorf("unexpected run %d, fork %v, options %v", idx, fk, opts.Snapshots)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 run, got %d", calls)
+	}
+}
+
+func TestForkOutput(t *testing.T) {
+	exp, err := experiment.New(nil, rand.New(rand.NewPCG(0, 0)), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observers := util.ObserversDef{
+		Tables: []util.TableDef{{
+			Observer:       "observers.Expressions",
+			ObserverConfig: json.RawMessage(`{"Columns": [{"Name": "Pollen", "Expr": "globals.Stores.Pollen"}]}`),
+			File:           "pollen.csv",
+		}},
+		EventLog: util.EventLogDef{Events: []string{events.PollenDepletion}},
+	}
+	forkDef := util.ForkDef{Tick: 6, Treatments: []util.TreatmentDef{{Name: "A"}, {Name: "B"}}}
+
+	ticks := map[int][]int{}
+	evts := map[int][]events.Event{}
+	err = Callback(context.Background(), &testParams{}, &exp, &observers, []app.System{&depletion{}},
+		nil, nil, snapshot.Options{}, &forkDef, 1, rand.New(rand.NewPCG(0, 0)), nil,
+		func(tables *util.Tables) error {
+			for _, row := range tables.Data[1] {
+				ticks[int(row[0])] = append(ticks[int(row[0])], int(row[1]))
+			}
+			for _, e := range tables.Events {
+				evts[e.Run] = append(evts[e.Run], e)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	for run := range forkDef.Treatments {
+		if !slices.Equal(ticks[run], expected) {
+			t.Errorf("expected ticks %v in run %d, got %v", expected, run, ticks[run])
+		}
+		if len(evts[run]) != 1 || evts[run][0].Type != events.PollenDepletion || evts[run][0].Tick != 3 {
+			t.Errorf("expected a single pollen depletion event at tick 3 in run %d, got %v", run, evts[run])
+		}
+	}
+}
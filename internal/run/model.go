@@ -1,62 +1,201 @@
 package run
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"path"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/mlange-42/ark-pixel/window"
 	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
 	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/events"
+	"github.com/mlange-42/beecs-cli/internal/edit"
+	"github.com/mlange-42/beecs-cli/internal/render"
+	"github.com/mlange-42/beecs-cli/internal/serve"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
 	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs-cli/registry"
 	"github.com/mlange-42/beecs/experiment"
 	"github.com/mlange-42/beecs/model"
 	"github.com/mlange-42/beecs/params"
 	butil "github.com/mlange-42/beecs/util"
 )
 
+// chunkSize is the number of table rows and events collected before they are written.
+const chunkSize = 1024
+
+// runOptions are the options of the runs of an experiment.
+type runOptions struct {
+	Observers *util.ObserversDef          // Observers of the runs.
+	Systems   []app.System                // Systems of the model. Uses the default systems if empty.
+	Overwrite []experiment.ParameterValue // Parameter values applied after the experiment's values.
+	Hooks     []util.HookDef              // Hooks called at points of the run lifecycle.
+	Snapshots snapshot.Options            // Options for starting from and saving snapshots.
+	Fork      *util.ForkDef               // Fork of runs into treatments. Optional.
+	NoUI      bool                        // Whether to run without UI.
+	OutDir    string                      // Output directory, for snapshots and animations.
+	RenderDir string                      // Directory for rendered frames. Not rendered if empty.
+	Server    *serve.Server               // Server for the live dashboard. Optional.
+	History   *render.History             // History for live plots across runs. Optional.
+	Edits     *edit.Session               // Session for live parameter editing. Optional.
+}
+
 func runModel(
+	ctx context.Context,
 	p params.Params,
 	exp *experiment.Experiment,
-	observers *util.ObserversDef,
-	systems []app.System,
-	overwrite []experiment.ParameterValue,
+	opts *runOptions,
+	fk *fork,
 	a *app.App,
-	idx int, rSeed int32, noUI bool,
+	idx int, rSeed int32,
+	write func(tables *util.Tables) error,
 ) (util.Tables, error) {
-	if len(systems) == 0 {
+	observers := opts.Observers
+	systems := opts.Systems
+	overwrite := opts.Overwrite
+	snapshots := opts.Snapshots
+	noUI, server, history := opts.NoUI, opts.Server, opts.History
+
+	var loader *snapshot.Loader
+	if len(systems) == 0 && snapshots.From == nil {
 		model.Default(p, a)
 	} else {
-		sysCopy := make([]app.System, len(systems))
-		// TODO: check copying!
-		for i, sys := range systems {
-			sysCopy[i] = butil.CopyInterface[app.System](sys)
+		var sysCopy []app.System
+		if len(systems) == 0 {
+			// Get the default systems, to prepend the snapshot loader.
+			model.Default(p, a)
+			sysCopy = append(sysCopy, a.Systems.Systems()...)
+		} else {
+			sysCopy = make([]app.System, len(systems))
+			// TODO: check copying!
+			for i, sys := range systems {
+				sysCopy[i] = butil.CopyInterface[app.System](sys)
+			}
+		}
+		if snapshots.From != nil {
+			loader = &snapshot.Loader{Snapshot: snapshots.From, Random: snapshots.Random}
+			sysCopy = append([]app.System{loader}, sysCopy...)
 		}
 		model.WithSystems(p, sysCopy, a)
 	}
 
+	// Errors during the run, like in table headers or when writing output, terminate the run.
+	// They are returned after it.
+	var runErr error
+	terminate := ecs.NewResource[resource.Termination](&a.World)
+	fail := func(err error) {
+		if runErr == nil {
+			runErr = err
+		}
+		terminate.Get().Terminate = true
+	}
+
+	// Table rows and events are collected in chunks and written during the run.
+	// The parameters table is returned in the result.
+	chunk := util.Tables{Index: idx}
+	rows := 0
+	flush := func() {
+		if runErr != nil {
+			return
+		}
+		if err := write(&chunk); err != nil {
+			fail(err)
+		}
+		for i := range chunk.Data {
+			chunk.Data[i] = chunk.Data[i][:0]
+		}
+		chunk.Events = chunk.Events[:0]
+		rows = 0
+	}
+
+	// The event log is present in every run, so that systems can emit events unconditionally.
+	ecs.AddResource(&a.World, events.NewLog(idx, func(e events.Event) {
+		chunk.Events = append(chunk.Events, e)
+		rows++
+		if rows >= chunkSize {
+			flush()
+		}
+	}))
+
+	runHooks, err := util.CreateHooks(opts.Hooks)
+	if err != nil {
+		return util.Tables{}, err
+	}
+	seedRes := ecs.GetResource[params.RandomSeed](&a.World)
+	callHooks := func(at registry.HookPoint) error {
+		seed := seedRes.Seed
+		if rSeed >= 0 && seed <= 0 {
+			seed = int(rSeed)
+		}
+		for _, h := range runHooks[at] {
+			if err := h.Run(idx, seed, &a.World); err != nil {
+				return fmt.Errorf("in hook at %s: %s", at, err.Error())
+			}
+		}
+		return nil
+	}
+	if err := callHooks(registry.AfterSetup); err != nil {
+		return util.Tables{}, err
+	}
+
+	// Forked runs use the parameter values of their base run, and apply the treatment's values as overwrites.
 	values := exp.Values(idx)
-	err := exp.ApplyValues(values, &a.World)
+	if fk != nil {
+		values = exp.Values(fk.Base)
+		overwrite = append(overwrite[:len(overwrite):len(overwrite)], fk.Def.Parameters...)
+	}
+	err = exp.ApplyValues(values, &a.World)
 	if err != nil {
 		return util.Tables{}, err
 	}
+	if err := callHooks(registry.AfterValues); err != nil {
+		return util.Tables{}, err
+	}
 
 	for _, par := range overwrite {
 		if err = model.SetParameter(&a.World, par.Parameter, par.Value); err != nil {
 			return util.Tables{}, err
 		}
 	}
+	if err := callHooks(registry.AfterOverwrites); err != nil {
+		return util.Tables{}, err
+	}
 
-	seedRes := ecs.GetResource[params.RandomSeed](&a.World)
 	if rSeed >= 0 && seedRes.Seed <= 0 {
 		seedRes.Seed = int(rSeed)
 		a.Seed(uint64(rSeed))
 	}
 
-	obs, err := observers.CreateObservers(!noUI)
+	// Tables report ticks relative to the start of the run, which is the snapshot's tick for warm starts.
+	var tickOffset int
+	if loader != nil {
+		tickOffset = int(snapshots.From.Tick)
+		seed := seedRes.Seed
+		// Parameters in the snapshot are replaced by the parameters, values and overwrites of this run.
+		loader.Apply = func(w *ecs.World) error {
+			if err := applyParams(p, w); err != nil {
+				return err
+			}
+			if err := exp.ApplyValues(values, w); err != nil {
+				return err
+			}
+			for _, par := range overwrite {
+				if err := model.SetParameter(w, par.Parameter, par.Value); err != nil {
+					return err
+				}
+			}
+			ecs.GetResource[params.RandomSeed](w).Seed = seed
+			return nil
+		}
+	}
+
+	obs, err := observers.CreateObservers(!noUI, history)
 	if err != nil {
-		log.Fatal(err)
+		return util.Tables{}, err
 	}
 
 	result := util.Tables{
@@ -74,9 +213,33 @@ func runModel(
 		floatValue := toFloat(v.Value)
 		result.Data[0][0] = append(result.Data[0][0], floatValue)
 	}
+	if fk != nil {
+		result.Headers[0] = append(result.Headers[0], "Treatment")
+		result.Data[0][0] = append(result.Data[0][0], float64(fk.Treatment))
+	}
+
+	chunk.Headers = result.Headers
+	chunk.Data = make([][][]float64, len(result.Data))
+
+	// The spin-up of forked runs does not finish, so final rows are not recorded.
+	spinUp := snapshots.SpinUp != nil
 
 	for i, t := range obs.Tables {
+		filter, err := util.NewTableFilter(&observers.Tables[i])
+		if err != nil {
+			return util.Tables{}, err
+		}
+		interval := alignInterval(&t.UpdateInterval, t.Final, tickOffset)
 		t.HeaderCallback = func(header []string) {
+			if err := util.ObserverError(t.Observer); err != nil {
+				fail(fmt.Errorf("in table '%s': %s", observers.Tables[i].File, err.Error()))
+				return
+			}
+			header, err := filter.Header(header)
+			if err != nil {
+				fail(fmt.Errorf("in table '%s': %s", observers.Tables[i].File, err.Error()))
+				return
+			}
 			h := make([]string, len(header)+2)
 			h[0] = "Run"
 			h[1] = "Ticks"
@@ -84,19 +247,33 @@ func runModel(
 			result.Headers[i+1] = h
 		}
 		t.Callback = func(step int, row []float64) {
+			step += tickOffset
+			if runErr != nil || (spinUp && t.Final) || step%interval != 0 || !filter.Accept(step) {
+				return
+			}
+			row = filter.Row(row)
 			data := make([]float64, len(row)+2)
 			data[0] = float64(idx)
 			data[1] = float64(step)
 			copy(data[2:], row)
 
-			result.Data[i+1] = append(result.Data[i+1], data)
+			chunk.Data[i+1] = append(chunk.Data[i+1], data)
+			rows++
+			if rows >= chunkSize {
+				flush()
+			}
 		}
 		a.AddSystem(t)
 	}
 
 	offset := len(obs.Tables)
 	for i, t := range obs.StepTables {
+		interval := alignInterval(&t.UpdateInterval, t.Final, tickOffset)
 		t.HeaderCallback = func(header []string) {
+			if err := util.ObserverError(t.Observer); err != nil {
+				fail(fmt.Errorf("in step table '%s': %s", observers.StepTables[i].File, err.Error()))
+				return
+			}
 			h := make([]string, len(header)+2)
 			h[0] = "Run"
 			h[1] = "Ticks"
@@ -104,29 +281,157 @@ func runModel(
 			result.Headers[offset+i+1] = h
 		}
 		t.Callback = func(step int, table [][]float64) {
+			step += tickOffset
+			if runErr != nil || (spinUp && t.Final) || step%interval != 0 {
+				return
+			}
 			for _, row := range table {
 				data := make([]float64, len(row)+2)
 				data[0] = float64(idx)
 				data[1] = float64(step)
 				copy(data[2:], row)
 
-				result.Data[offset+i+1] = append(result.Data[offset+i+1], data)
+				chunk.Data[offset+i+1] = append(chunk.Data[offset+i+1], data)
+			}
+			rows += len(table)
+			if rows >= chunkSize {
+				flush()
 			}
 		}
 		a.AddSystem(t)
 	}
 
+	// Treatments record the output of their spin-up first, as their own.
+	// It is replayed after the table headers are known, so that it can be written in chunks.
+	if fk != nil {
+		a.AddSystem(&initializer{Init: func() {
+			err := fk.SpinUp.replay(idx,
+				func(table int, row []float64) {
+					chunk.Data[table] = append(chunk.Data[table], row)
+					rows++
+					if rows >= chunkSize {
+						flush()
+					}
+				},
+				func(e events.Event) {
+					chunk.Events = append(chunk.Events, e)
+					rows++
+					if rows >= chunkSize {
+						flush()
+					}
+				})
+			if err != nil {
+				fail(err)
+			}
+		}})
+	}
+
+	// Systems that record errors during the run, to be returned after it.
+	failing := []interface{ Err() error }{}
+	if loader != nil {
+		failing = append(failing, loader)
+	}
+	for _, d := range obs.Drawers {
+		failing = append(failing, d)
+	}
+
+	detector, err := observers.CreateEventDetector()
+	if err != nil {
+		return util.Tables{}, err
+	}
+	if detector != nil {
+		a.AddSystem(detector)
+	}
+
+	if opts.RenderDir != "" {
+		renderers, err := observers.CreateRenderers(path.Join(opts.RenderDir, fmt.Sprintf("run-%05d", idx)))
+		if err != nil {
+			return util.Tables{}, err
+		}
+		for _, r := range renderers {
+			a.AddSystem(r)
+			failing = appendFailing(failing, r)
+		}
+	}
+
+	animations, err := observers.CreateAnimations(opts.OutDir, idx)
+	if err != nil {
+		return util.Tables{}, err
+	}
+	for _, s := range animations {
+		a.AddSystem(s)
+		failing = appendFailing(failing, s)
+	}
+
+	if server != nil {
+		dashboard, err := observers.CreateDashboard(server, idx, fail)
+		if err != nil {
+			return util.Tables{}, err
+		}
+		for _, s := range dashboard {
+			a.AddSystem(s)
+			failing = appendFailing(failing, s)
+		}
+		a.AddUISystem(&serve.Controls{Server: server, Run: idx})
+	}
+
+	if opts.Edits != nil && (!noUI || server != nil) {
+		a.AddUISystem(opts.Edits.Editor(idx))
+	}
+
+	if ctx.Done() != nil {
+		a.AddSystem(&cancel{Context: ctx})
+	}
+
 	if !noUI {
 		for _, p := range obs.Windows {
 			a.AddUISystem(p)
 		}
 	}
 
+	if snapshots.File != "" {
+		saver := &snapshot.Saver{File: snapshots.RunFile(opts.OutDir, idx), Tick: snapshots.Tick}
+		a.AddSystem(saver)
+		failing = append(failing, saver)
+	}
+	if snapshots.SpinUp != nil {
+		a.AddSystem(snapshots.SpinUp)
+		failing = append(failing, snapshots.SpinUp)
+	}
+
+	if err := callHooks(registry.BeforeRun); err != nil {
+		return util.Tables{}, err
+	}
+
 	if noUI {
 		a.Run()
 	} else {
 		window.Run(a)
 	}
+	for _, f := range failing {
+		if err := f.Err(); err != nil {
+			fail(err)
+		}
+	}
+	if rows > 0 {
+		flush()
+	}
+	if runErr != nil {
+		return util.Tables{}, runErr
+	}
+	if spinUp {
+		return result, nil
+	}
+	if history != nil {
+		label := setLabel(values)
+		if fk != nil {
+			label = strings.TrimPrefix(label+", "+fk.Def.Name, ", ")
+		}
+		history.Finish(label)
+	}
+	if err := callHooks(registry.AfterFinish); err != nil {
+		return util.Tables{}, err
+	}
 
 	now = time.Now().UnixMilli()
 	result.Data[0][0][3] = float64(now)
@@ -134,6 +439,68 @@ func runModel(
 	return result, nil
 }
 
+// appendFailing appends a system to the list of systems that record errors, if it does so.
+func appendFailing(failing []interface{ Err() error }, sys app.System) []interface{ Err() error } {
+	if f, ok := sys.(interface{ Err() error }); ok {
+		return append(failing, f)
+	}
+	return failing
+}
+
+// applyParams sets the parameter resources of a world to the values of the given parameters,
+// e.g. after restoring a snapshot that was taken with other parameters.
+//
+// Parameters are applied to a temporary world, and copied to resources of the same type,
+// as resources can't be added to a world twice.
+func applyParams(p params.Params, w *ecs.World) error {
+	temp := ecs.NewWorld()
+	p.Apply(&temp)
+
+	ids := map[reflect.Type]ecs.ResID{}
+	for _, id := range ecs.ResourceIDs(w) {
+		if tp, ok := ecs.ResourceType(w, id); ok {
+			ids[tp] = id
+		}
+	}
+	tempRes := temp.Resources()
+	res := w.Resources()
+	for _, id := range ecs.ResourceIDs(&temp) {
+		if !tempRes.Has(id) {
+			continue
+		}
+		tp, _ := ecs.ResourceType(&temp, id)
+		target, ok := ids[tp]
+		if !ok || !res.Has(target) {
+			return fmt.Errorf("parameter resource %s not found in the world", tp.String())
+		}
+		reflect.ValueOf(res.Get(target)).Elem().Set(reflect.ValueOf(tempRes.Get(id)).Elem())
+	}
+	return nil
+}
+
+// alignInterval aligns the rows of a table to absolute ticks that are multiples of its update interval,
+// for runs that start at the given tick offset, like warm starts from a snapshot.
+//
+// If the offset is not on the interval, the table's reporter is set to update on every tick,
+// and the returned interval must be used to select rows by their absolute tick. Otherwise, it returns 1.
+func alignInterval(updateInterval *int, final bool, tickOffset int) int {
+	interval := max(*updateInterval, 1)
+	if final || tickOffset%interval == 0 {
+		return 1
+	}
+	*updateInterval = 1
+	return interval
+}
+
+// setLabel creates a label for the parameter set of a run, for live plots across runs.
+func setLabel(values []experiment.ParameterValue) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%s=%v", v.Parameter, v.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
 func toFloat(v any) float64 {
 	var floatValue float64
 	switch vv := v.(type) {
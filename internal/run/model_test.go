@@ -0,0 +1,90 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/params"
+)
+
+// testParams are parameters for tests, with a single value.
+type testParams struct {
+	Seed  params.RandomSeed
+	Value testValue
+}
+
+type testValue struct {
+	Value float64
+}
+
+func (p *testParams) Apply(w *ecs.World) {
+	seed, value := p.Seed, p.Value
+	ecs.AddResource(w, &seed)
+	ecs.AddResource(w, &value)
+	ecs.AddResource(w, &params.Nursing{MaxBroodNurseRatio: 3, ForagerNursingContribution: 0.5})
+}
+
+func (p *testParams) FromJSONFile(path string) error { return nil }
+
+func (p *testParams) FromJSON(data []byte) error { return nil }
+
+func TestApplyParams(t *testing.T) {
+	w := ecs.NewWorld()
+	snapshotParams := testParams{Seed: params.RandomSeed{Seed: 1}, Value: testValue{Value: 1}}
+	snapshotParams.Apply(&w)
+
+	runParams := testParams{Seed: params.RandomSeed{Seed: 2}, Value: testValue{Value: 2}}
+	if err := applyParams(&runParams, &w); err != nil {
+		t.Fatal(err)
+	}
+	if seed := ecs.GetResource[params.RandomSeed](&w).Seed; seed != 2 {
+		t.Errorf("expected seed 2, got %d", seed)
+	}
+	if value := ecs.GetResource[testValue](&w).Value; value != 2 {
+		t.Errorf("expected value 2, got %f", value)
+	}
+
+	// Changes to the applied values must not affect the parameters.
+	ecs.GetResource[testValue](&w).Value = 3
+	if runParams.Value.Value != 2 {
+		t.Errorf("parameters were changed via the world")
+	}
+
+	empty := ecs.NewWorld()
+	if err := applyParams(&runParams, &empty); err == nil {
+		t.Error("expected error for parameter resources missing in the world")
+	}
+}
+
+func TestAlignInterval(t *testing.T) {
+	tests := []struct {
+		name           string
+		updateInterval int
+		final          bool
+		offset         int
+		interval       int // Expected interval for selecting rows.
+		reporter       int // Expected update interval of the reporter.
+	}{
+		{"cold start", 7, false, 0, 1, 7},
+		{"aligned", 7, false, 735, 1, 7},
+		{"misaligned", 7, false, 730, 7, 1},
+		{"default interval", 0, false, 730, 1, 0},
+		{"final", 7, true, 730, 1, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := tt.updateInterval
+			interval := alignInterval(&reporter, tt.final, tt.offset)
+			if interval != tt.interval || reporter != tt.reporter {
+				t.Errorf("expected intervals %d and %d, got %d and %d", tt.interval, tt.reporter, interval, reporter)
+			}
+			// Rows of the reporter, shifted by the offset, must land on multiples of the update interval.
+			for step := 0; step < 30; step += max(reporter, 1) {
+				tick := step + tt.offset
+				if !tt.final && tick%interval == 0 && tick%max(tt.updateInterval, 1) != 0 {
+					t.Errorf("row at tick %d is not on the update interval %d", tick, tt.updateInterval)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package run
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math/rand/v2"
 	"path"
+	"sync"
 
 	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
 	"github.com/mlange-42/beecs-cli/internal/util"
 	"github.com/mlange-42/beecs/experiment"
 	"github.com/mlange-42/beecs/params"
@@ -17,6 +19,32 @@ type job struct {
 	Seed  int32
 }
 
+type runResult struct {
+	Tables util.Tables       // Headers and parameters of the run.
+	Temp   util.CsvWriter    // Temporary files holding the run's table output.
+	Events *util.EventWriter // Temporary file holding the run's events. Nil if there is no event log.
+	Err    error             // Error of the run. Other fields are empty if set.
+}
+
+// close the temporary writers of the result.
+func (r *runResult) close() error {
+	if err := r.Temp.Close(); err != nil {
+		return err
+	}
+	if r.Events != nil {
+		return r.Events.Close()
+	}
+	return nil
+}
+
+// remove the temporary files of the result.
+func (r *runResult) remove() {
+	r.Temp.Remove()
+	if r.Events != nil {
+		r.Events.Remove()
+	}
+}
+
 // Parallel runs experiments in parallel.
 func Parallel(
 	p params.Params,
@@ -24,40 +52,21 @@ func Parallel(
 	observers *util.ObserversDef,
 	systems []app.System,
 	overwrite []experiment.ParameterValue,
+	hooks []util.HookDef,
+	snapshots snapshot.Options,
+	forkDef *util.ForkDef,
 	dir string,
 	threads int, tps float64, rng *rand.Rand,
 	indices []int,
+	renderDir string,
 ) error {
 	maxRuns := exp.TotalRuns()
 	totalRuns := maxRuns
 	if len(indices) > 0 {
 		totalRuns = len(indices)
 	}
-
-	// Channel for sending jobs to workers (buffered!).
-	jobs := make(chan job, totalRuns)
-	// Channel for retrieving results / done messages (buffered!).
-	results := make(chan util.Tables, totalRuns)
-
-	seeds := make([]int32, maxRuns)
-	for i := range seeds {
-		seeds[i] = rng.Int32()
-	}
-
-	// Start the workers.
-	for w := 0; w < threads; w++ {
-		go worker(jobs, results, p, exp, observers, systems, overwrite, tps)
-	}
-
-	// Send the jobs. Does not block due to buffered channel.
-	err := iterate(maxRuns, indices, func(idx int) error {
-		jobs <- job{Index: idx, Seed: seeds[idx]}
-		return nil
-	})
-	if err != nil {
-		return err
-	}
-	close(jobs)
+	// Each job results in one run per treatment if runs are forked.
+	totalResults := totalRuns * forkDef.Forks()
 
 	paramsFile := observers.Parameters
 	if len(paramsFile) == 0 {
@@ -76,40 +85,152 @@ func Parallel(
 	if err != nil {
 		return err
 	}
+	eventWriter, err := observers.CreateEventWriter(dir)
+	if err != nil {
+		return err
+	}
 
-	// Collect done messages.
-	err = iterate(maxRuns, indices, func(idx int) error {
-		result := <-results
-		err = writer.Write(&result)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("Run %5d/%d\n", result.Index, totalRuns)
+	// Channel for sending jobs to workers (buffered!).
+	jobs := make(chan job, totalRuns)
+	// Channel for retrieving results / done messages (buffered!).
+	results := make(chan runResult, totalResults)
+
+	seeds := runSeeds(maxRuns, rng)
+
+	// Start the workers. Remaining jobs are skipped after an error.
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	opts := runOptions{
+		Observers: observers,
+		Systems:   systems,
+		Overwrite: overwrite,
+		Hooks:     hooks,
+		Snapshots: snapshots,
+		Fork:      forkDef,
+		NoUI:      true,
+		OutDir:    dir,
+		RenderDir: renderDir,
+	}
+	wg := sync.WaitGroup{}
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx, cancelFn, jobs, results, p, exp, &opts, eventWriter, tps)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Send the jobs. Does not block due to buffered channel.
+	_ = iterate(maxRuns, indices, func(idx int) error {
+		jobs <- job{Index: idx, Seed: seeds[idx]}
 		return nil
 	})
-	if err != nil {
-		return err
+	close(jobs)
+
+	// Collect done messages. After the first error, temporary files of further results are discarded.
+	var runErr error
+	for result := range results {
+		if runErr == nil {
+			runErr = result.Err
+		}
+		if runErr != nil {
+			cancelFn()
+			result.remove()
+			continue
+		}
+		if err = writer.Append(&result.Tables, &result.Temp); err != nil {
+			runErr = err
+			cancelFn()
+			if result.Events != nil {
+				result.Events.Remove()
+			}
+			continue
+		}
+		if result.Events != nil {
+			if err = eventWriter.Append(result.Events); err != nil {
+				runErr = err
+				cancelFn()
+				continue
+			}
+		}
+		fmt.Printf("Run %5d/%d\n", result.Tables.Index, totalResults)
+	}
+	if runErr != nil {
+		writer.Close()
+		if eventWriter != nil {
+			eventWriter.Close()
+		}
+		return runErr
 	}
 
+	if eventWriter != nil {
+		if err = eventWriter.Close(); err != nil {
+			return err
+		}
+	}
 	return writer.Close()
 }
 
-func worker(jobs <-chan job, results chan<- util.Tables,
-	p params.Params, exp *experiment.Experiment, observers *util.ObserversDef,
-	systems []app.System, overwrite []experiment.ParameterValue, tps float64) {
+// worker processes jobs, and sends a result for each finished run.
+// On an error, a result with the error is sent, and the context is cancelled.
+func worker(ctx context.Context, cancelFn context.CancelFunc, jobs <-chan job, results chan<- runResult,
+	p params.Params, exp *experiment.Experiment, opts *runOptions, eventWriter *util.EventWriter, tps float64) {
 
 	m := app.New()
 	m.FPS = 30
 	m.TPS = tps
 
+	numTables := len(opts.Observers.Tables) + len(opts.Observers.StepTables) + 1
+
 	// Process incoming jobs.
 	for j := range jobs {
-		// Run the model.
-		res, err := runModel(p, exp, observers, systems, overwrite, m, j.Index, j.Seed, true)
+		if ctx.Err() != nil {
+			continue
+		}
+		err := forEachRun(ctx, p, exp, opts, m, j.Index, j.Seed,
+			func(run int, fk *fork, opts *runOptions) error {
+				// Buffer table output and events on disk, as runs finish in arbitrary order.
+				result := runResult{}
+				var err error
+				result.Temp, err = util.NewTempCsvWriter(numTables, opts.Observers.CsvSeparator)
+				if err != nil {
+					return err
+				}
+				if eventWriter != nil {
+					if result.Events, err = eventWriter.Temp(); err != nil {
+						result.remove()
+						return err
+					}
+				}
+				write := func(tables *util.Tables) error {
+					if err := result.Temp.Write(tables); err != nil {
+						return err
+					}
+					if result.Events != nil {
+						return result.Events.Write(tables.Events)
+					}
+					return nil
+				}
+				// Run the model.
+				result.Tables, err = runModel(ctx, p, exp, opts, fk, m, run, j.Seed, write)
+				if err == nil {
+					err = result.close()
+				}
+				if err != nil {
+					result.remove()
+					return err
+				}
+				// Send done message. Does not block due to buffered channel.
+				results <- result
+				return nil
+			})
 		if err != nil {
-			log.Fatal(err)
+			cancelFn()
+			results <- runResult{Err: err}
 		}
-		// Send done message. Does not block due to buffered channel.
-		results <- res
 	}
 }
@@ -1,11 +1,16 @@
 package run
 
 import (
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"path"
 
 	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/beecs-cli/internal/edit"
+	"github.com/mlange-42/beecs-cli/internal/render"
+	"github.com/mlange-42/beecs-cli/internal/serve"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
 	"github.com/mlange-42/beecs-cli/internal/util"
 	"github.com/mlange-42/beecs/experiment"
 	"github.com/mlange-42/beecs/params"
@@ -18,9 +23,15 @@ func Sequential(
 	observers *util.ObserversDef,
 	systems []app.System,
 	overwrite []experiment.ParameterValue,
+	hooks []util.HookDef,
+	snapshots snapshot.Options,
+	forkDef *util.ForkDef,
 	dir string,
 	tps float64, rng *rand.Rand,
-	indices []int,
+	indices []int, noUI bool,
+	renderDir string,
+	server *serve.Server,
+	edits *edit.Session,
 ) error {
 	m := app.New()
 	m.FPS = 30
@@ -43,31 +54,89 @@ func Sequential(
 	if err != nil {
 		return err
 	}
+	eventWriter, err := observers.CreateEventWriter(dir)
+	if err != nil {
+		return err
+	}
+
+	// Events are written along with the chunks of table rows.
+	write := func(tables *util.Tables) error {
+		if err := writer.Write(tables); err != nil {
+			return err
+		}
+		if eventWriter != nil {
+			return eventWriter.Write(tables.Events)
+		}
+		return nil
+	}
 
 	maxRuns := exp.TotalRuns()
 	actualRuns := maxRuns
 	if len(indices) > 0 {
 		actualRuns = len(indices)
 	}
+	seeds := runSeeds(maxRuns, rng)
+	totalRuns := maxRuns * forkDef.Forks()
+	actualRuns *= forkDef.Forks()
+
+	// Live plots are shown for multiple runs only if there are plots across runs.
+	var history *render.History
+	if !noUI && actualRuns > 1 && observers.MultiRunPlots() {
+		history = render.NewHistory()
+	}
+	runNoUI := noUI || (actualRuns > 1 && history == nil)
+
+	opts := runOptions{
+		Observers: observers,
+		Systems:   systems,
+		Overwrite: overwrite,
+		Hooks:     hooks,
+		Snapshots: snapshots,
+		Fork:      forkDef,
+		NoUI:      runNoUI,
+		OutDir:    dir,
+		RenderDir: renderDir,
+		Server:    server,
+		History:   history,
+		Edits:     edits,
+	}
 	err = iterate(maxRuns, indices, func(idx int) error {
-		result, err := runModel(p, exp, observers, systems, overwrite, m, idx, rng.Int32(), actualRuns > 1)
-		if err != nil {
-			return err
-		}
-		err = writer.Write(&result)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("Run %5d/%d\n", idx, maxRuns)
-		return nil
+		return forEachRun(context.Background(), p, exp, &opts, m, idx, seeds[idx],
+			func(run int, fk *fork, opts *runOptions) error {
+				result, err := runModel(context.Background(), p, exp, opts, fk, m, run, seeds[idx], write)
+				if err != nil {
+					return err
+				}
+				err = writer.Write(&result)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Run %5d/%d\n", run, totalRuns)
+				return nil
+			})
 	})
 	if err != nil {
 		return err
 	}
 
+	if eventWriter != nil {
+		if err = eventWriter.Close(); err != nil {
+			return err
+		}
+	}
 	return writer.Close()
 }
 
+// runSeeds generates the random seeds for all runs of an experiment.
+// Seeds are generated for all runs, so that a run's seed does not depend on the selected indices.
+func runSeeds(totalRuns int, rng *rand.Rand) []int32 {
+	seeds := make([]int32, totalRuns)
+	for i := range seeds {
+		seeds[i] = rng.Int32()
+	}
+	return seeds
+}
+
 func iterate(totalRuns int, indices []int, fn func(idx int) error) error {
 	if len(indices) == 0 {
 		for j := 0; j < totalRuns; j++ {
@@ -0,0 +1,192 @@
+package serve
+
+// page is the dashboard page. Plots are drawn client-side, on HTML canvases.
+const page = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>beecs live</title>
+<style>
+body { font-family: sans-serif; margin: 0; background: #111; color: #ddd; }
+header { padding: 0.5em 1em; background: #222; display: flex; gap: 1em; align-items: center; }
+button { font-size: 100%; padding: 0.2em 1em; }
+main { display: flex; flex-wrap: wrap; gap: 1em; padding: 1em; }
+figure { margin: 0; background: #000; }
+figcaption { padding: 0.2em 0.5em; background: #222; }
+canvas, img { display: block; }
+#status { margin-left: auto; }
+</style>
+</head>
+<body>
+<header>
+<button id="play">Play</button>
+<button id="pause">Pause</button>
+<button id="step">Step</button>
+<span id="status">Connecting...</span>
+</header>
+<main id="plots"></main>
+<script>
+const colors = ["#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#46f0f0", "#f032e6", "#bcf60c", "#fabebe", "#008080"];
+let layout = null;
+let series = [];
+let tables = [];
+let canvases = { ts: [], lines: [] };
+let images = [];
+let dirty = new Set();
+
+function post(cmd) { fetch("control/" + cmd, { method: "POST" }); }
+document.getElementById("play").onclick = () => post("play");
+document.getElementById("pause").onclick = () => post("pause");
+document.getElementById("step").onclick = () => post("step");
+
+function figure(title, elem) {
+	const fig = document.createElement("figure");
+	const cap = document.createElement("figcaption");
+	cap.textContent = title;
+	fig.appendChild(cap);
+	fig.appendChild(elem);
+	document.getElementById("plots").appendChild(fig);
+}
+
+function canvas() {
+	const c = document.createElement("canvas");
+	c.width = 600; c.height = 400;
+	return c;
+}
+
+function buildLayout(l) {
+	const same = layout && layout.Run === l.Run &&
+		layout.TimeSeries.length === l.TimeSeries.length &&
+		layout.Lines.length === l.Lines.length && layout.Views.length === l.Views.length;
+	layout = l;
+	if (same) { redrawAll(); return; }
+
+	document.getElementById("plots").innerHTML = "";
+	series = l.TimeSeries.map(() => []);
+	tables = l.Lines.map(() => null);
+	canvases = { ts: [], lines: [] };
+	images = [];
+	l.TimeSeries.forEach(p => { const c = canvas(); canvases.ts.push(c); figure(p.Title, c); });
+	l.Lines.forEach(p => { const c = canvas(); canvases.lines.push(c); figure(p.Title, c); });
+	l.Views.forEach(p => { const img = document.createElement("img"); images.push(img); figure(p.Title, img); });
+}
+
+function redrawAll() {
+	series.forEach((_, i) => dirty.add("ts" + i));
+	tables.forEach((_, i) => dirty.add("lines" + i));
+}
+
+function drawPlot(c, p, lines, xLim, yLim) {
+	const ctx = c.getContext("2d");
+	const m = { l: 60, r: 120, t: 10, b: 40 };
+	const w = c.width - m.l - m.r, h = c.height - m.t - m.b;
+	ctx.fillStyle = "#000"; ctx.fillRect(0, 0, c.width, c.height);
+
+	let [x0, x1] = xLim, [y0, y1] = yLim;
+	if (x0 === 0 && x1 === 0) { x0 = Infinity; x1 = -Infinity; }
+	if (y0 === 0 && y1 === 0) { y0 = Infinity; y1 = -Infinity; }
+	const autoX = x0 === Infinity, autoY = y0 === Infinity;
+	for (const line of lines) {
+		for (const [x, y] of line.points) {
+			if (y === null) continue;
+			if (autoX) { x0 = Math.min(x0, x); x1 = Math.max(x1, x); }
+			if (autoY) { y0 = Math.min(y0, y); y1 = Math.max(y1, y); }
+		}
+	}
+	if (!isFinite(x0) || !isFinite(y0)) return;
+	if (x1 === x0) x1 = x0 + 1;
+	if (y1 === y0) y1 = y0 + 1;
+	const sx = x => m.l + (x - x0) / (x1 - x0) * w;
+	const sy = y => m.t + h - (y - y0) / (y1 - y0) * h;
+
+	ctx.strokeStyle = "#666"; ctx.fillStyle = "#aaa"; ctx.font = "11px sans-serif";
+	ctx.strokeRect(m.l, m.t, w, h);
+	for (let i = 0; i <= 4; i++) {
+		const xv = x0 + (x1 - x0) * i / 4, yv = y0 + (y1 - y0) * i / 4;
+		ctx.textAlign = "center"; ctx.fillText(+xv.toPrecision(4), sx(xv), m.t + h + 14);
+		ctx.textAlign = "right"; ctx.fillText(+yv.toPrecision(4), m.l - 4, sy(yv) + 4);
+	}
+	ctx.textAlign = "center"; ctx.fillText(p.X, m.l + w / 2, c.height - 6);
+	ctx.save(); ctx.translate(12, m.t + h / 2); ctx.rotate(-Math.PI / 2); ctx.fillText(p.Y, 0, 0); ctx.restore();
+
+	ctx.save();
+	ctx.beginPath(); ctx.rect(m.l, m.t, w, h); ctx.clip();
+	lines.forEach((line, i) => {
+		ctx.strokeStyle = colors[i % colors.length];
+		ctx.beginPath();
+		let pen = false;
+		for (const [x, y] of line.points) {
+			if (y === null) { pen = false; continue; }
+			if (pen) ctx.lineTo(sx(x), sy(y)); else ctx.moveTo(sx(x), sy(y));
+			pen = true;
+		}
+		ctx.stroke();
+	});
+	ctx.restore();
+
+	ctx.textAlign = "left";
+	lines.forEach((line, i) => {
+		ctx.fillStyle = colors[i % colors.length];
+		ctx.fillRect(m.l + w + 10, m.t + 6 + i * 16, 10, 3);
+		ctx.fillText(line.name, m.l + w + 24, m.t + 10 + i * 16);
+	});
+}
+
+function drawTimeSeries(i) {
+	const p = layout.TimeSeries[i];
+	if (!p.Columns) return;
+	const lines = p.Columns.map((name, j) => ({ name: name, points: series[i].map(r => [r.Tick, r.Values[j]]) }));
+	drawPlot(canvases.ts[i], p, lines, [0, 0], [0, 0]);
+}
+
+function drawLines(i) {
+	const p = layout.Lines[i], t = tables[i];
+	if (!t || !p.Columns) return;
+	const xIdx = p.XColumn ? p.Columns.indexOf(p.XColumn) : -1;
+	const yCols = p.YColumns && p.YColumns.length ? p.YColumns : p.Columns.filter((_, j) => j !== xIdx);
+	const lines = yCols.map(name => {
+		const j = p.Columns.indexOf(name);
+		return { name: name, points: t.Data.map((row, r) => [xIdx >= 0 ? row[xIdx] : r, row[j]]) };
+	});
+	drawPlot(canvases.lines[i], p, lines, p.XLim, p.YLim);
+}
+
+function frame() {
+	for (const key of dirty) {
+		if (key.startsWith("ts")) drawTimeSeries(+key.slice(2));
+		else drawLines(+key.slice(5));
+	}
+	dirty.clear();
+	requestAnimationFrame(frame);
+}
+requestAnimationFrame(frame);
+
+const events = new EventSource("events");
+events.addEventListener("layout", e => buildLayout(JSON.parse(e.data)));
+events.addEventListener("state", e => {
+	const s = JSON.parse(e.data);
+	document.getElementById("status").textContent = "Run " + s.Run + ", tick " + s.Tick + (s.Paused ? " (paused)" : "");
+});
+events.addEventListener("row", e => {
+	const r = JSON.parse(e.data);
+	const rows = series[r.Plot];
+	if (rows.length > 0 && rows[rows.length - 1].Tick >= r.Tick) rows.length = 0;
+	rows.push(r);
+	const max = layout.TimeSeries[r.Plot].MaxRows;
+	if (max > 0 && rows.length > max) rows.shift();
+	dirty.add("ts" + r.Plot);
+});
+events.addEventListener("table", e => {
+	const t = JSON.parse(e.data);
+	tables[t.Plot] = t;
+	dirty.add("lines" + t.Plot);
+});
+events.addEventListener("view", e => {
+	const v = JSON.parse(e.data);
+	images[v.View].src = "views/" + v.View + "?frame=" + v.Frame;
+});
+events.onerror = () => { document.getElementById("status").textContent = "Disconnected"; };
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,392 @@
+// Package serve provides a live dashboard in the browser, using Server-Sent Events.
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// clientBuffer is the number of events buffered per client.
+// Clients that can't keep up are disconnected, and receive the full state when reconnecting.
+const clientBuffer = 1024
+
+// Commands sent from the browser.
+const (
+	CommandPlay  = "play"
+	CommandPause = "pause"
+	CommandStep  = "step"
+)
+
+type event struct {
+	Name string
+	Data []byte
+}
+
+// Layout describes the plots and views of a dashboard.
+type Layout struct {
+	Run        int          // Index of the current run.
+	TimeSeries []PlotLayout // Time series plots.
+	Lines      []PlotLayout // Line plots.
+	Views      []PlotLayout // Views.
+}
+
+// PlotLayout describes a single plot or view.
+type PlotLayout struct {
+	Title    string
+	X, Y     string     // Axis labels.
+	Columns  []string   // Column names of the data.
+	XColumn  string     // X column of line plots. Default: row index.
+	YColumns []string   // Y columns of line plots. Default: all but XColumn.
+	XLim     [2]float64 // X axis limits. Default: auto.
+	YLim     [2]float64 // Y axis limits. Default: auto.
+	MaxRows  int        // Maximum number of rows of time series. Default: unlimited.
+}
+
+// State is the simulation state shown in the dashboard.
+type State struct {
+	Run    int
+	Tick   int64
+	Paused bool
+}
+
+type rowData struct {
+	Plot   int
+	Tick   int
+	Values values
+}
+
+type tableData struct {
+	Plot int
+	Tick int
+	Data []values
+}
+
+// values is a slice of floats that encodes NaN and infinite values as JSON null.
+type values []float64
+
+func (v values) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	for i, f := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			b = append(b, "null"...)
+			continue
+		}
+		b = strconv.AppendFloat(b, f, 'g', -1, 64)
+	}
+	return append(b, ']'), nil
+}
+
+type viewData struct {
+	View  int
+	Frame int
+}
+
+// Server serves the dashboard page, an event stream with plot data, and images of views.
+//
+// The server is safe for concurrent use. Data is fed by the systems created by the run
+// via the Set*, Add* and Update* methods, while commands from the browser are polled via [Server.Commands].
+type Server struct {
+	addr     string
+	listener net.Listener
+	commands chan string
+
+	mu      sync.Mutex
+	clients map[chan event]struct{}
+	layout  Layout
+	state   State
+	rows    [][]rowData
+	tables  []*tableData
+	views   [][]byte
+	frames  []int
+}
+
+// NewServer creates a new server for the given address, like ":8080".
+func NewServer(addr string) *Server {
+	return &Server{
+		addr:     addr,
+		commands: make(chan string, 64),
+		clients:  map[chan event]struct{}{},
+	}
+}
+
+// Start listening and serving in the background.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	handler := s.handler()
+	go func() {
+		if err := http.Serve(listener, handler); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Println(err)
+		}
+	}()
+	return nil
+}
+
+// handler creates the handler for all routes of the server.
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handlePage)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /views/{index}", s.handleView)
+	mux.HandleFunc("POST /control/{command}", s.handleControl)
+	return mux
+}
+
+// Close the server.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// URL of the dashboard.
+func (s *Server) URL() string {
+	_, port, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil {
+		return s.addr
+	}
+	return fmt.Sprintf("http://localhost:%s/", port)
+}
+
+// Commands returns the channel of commands received from the browser.
+func (s *Server) Commands() <-chan string {
+	return s.commands
+}
+
+// Reset the dashboard for a new run with the given layout.
+func (s *Server) Reset(layout Layout) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Avoid null in JSON, for simpler handling in the browser.
+	for _, l := range []*[]PlotLayout{&layout.TimeSeries, &layout.Lines, &layout.Views} {
+		if *l == nil {
+			*l = []PlotLayout{}
+		}
+	}
+	s.layout = layout
+	s.state = State{Run: layout.Run}
+	s.rows = make([][]rowData, len(layout.TimeSeries))
+	s.tables = make([]*tableData, len(layout.Lines))
+	s.views = make([][]byte, len(layout.Views))
+	s.frames = make([]int, len(layout.Views))
+	s.broadcast(mustEvent("layout", &s.layout))
+	s.broadcast(mustEvent("state", &s.state))
+}
+
+// SetColumns sets the columns of a time series or line plot, after initialization of its observer.
+func (s *Server) SetColumns(lines bool, plot int, columns []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lines {
+		s.layout.Lines[plot].Columns = columns
+	} else {
+		s.layout.TimeSeries[plot].Columns = columns
+	}
+	s.broadcast(mustEvent("layout", &s.layout))
+}
+
+// AddRow adds a row to a time series plot.
+func (s *Server) AddRow(plot int, tick int, values []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := rowData{Plot: plot, Tick: tick, Values: append([]float64{}, values...)}
+	s.rows[plot] = append(s.rows[plot], row)
+	if max := s.layout.TimeSeries[plot].MaxRows; max > 0 && len(s.rows[plot]) > max {
+		s.rows[plot] = s.rows[plot][1:]
+	}
+	s.broadcast(mustEvent("row", &row))
+}
+
+// UpdateTable replaces the data of a line plot.
+func (s *Server) UpdateTable(plot int, tick int, data [][]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table := tableData{Plot: plot, Tick: tick, Data: make([]values, len(data))}
+	for i, row := range data {
+		table.Data[i] = append([]float64{}, row...)
+	}
+	s.tables[plot] = &table
+	s.broadcast(mustEvent("table", &table))
+}
+
+// UpdateView replaces the PNG image of a view.
+func (s *Server) UpdateView(view int, png []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.views[view] = png
+	s.frames[view]++
+	s.broadcast(mustEvent("view", &viewData{View: view, Frame: s.frames[view]}))
+}
+
+// UpdateState updates the simulation state.
+func (s *Server) UpdateState(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state == s.state {
+		return
+	}
+	s.state = state
+	s.broadcast(mustEvent("state", &s.state))
+}
+
+// HasClients returns whether any browser is connected.
+func (s *Server) HasClients() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients) > 0
+}
+
+// broadcast sends an event to all clients. Must be called with the lock held.
+func (s *Server) broadcast(e event) {
+	for ch := range s.clients {
+		select {
+		case ch <- e:
+		default:
+			delete(s.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// snapshot creates events for the full current state. Must be called with the lock held.
+func (s *Server) snapshot() []event {
+	events := []event{
+		mustEvent("layout", &s.layout),
+		mustEvent("state", &s.state),
+	}
+	for _, rows := range s.rows {
+		for i := range rows {
+			events = append(events, mustEvent("row", &rows[i]))
+		}
+	}
+	for _, t := range s.tables {
+		if t != nil {
+			events = append(events, mustEvent("table", t))
+		}
+	}
+	for i, v := range s.views {
+		if v != nil {
+			events = append(events, mustEvent("view", &viewData{View: i, Frame: s.frames[i]}))
+		}
+	}
+	return events
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(page))
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch := make(chan event, clientBuffer)
+	s.mu.Lock()
+	initial := s.snapshot()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.clients[ch]; ok {
+			delete(s.clients, ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	for _, e := range initial {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	var index int
+	if _, err := fmt.Sscan(r.PathValue("index"), &index); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	var img []byte
+	if index >= 0 && index < len(s.views) {
+		img = s.views[index]
+	}
+	s.mu.Unlock()
+
+	if img == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(img)
+}
+
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	cmd := r.PathValue("command")
+	switch cmd {
+	case CommandPlay, CommandPause, CommandStep:
+	default:
+		http.Error(w, fmt.Sprintf("unknown command '%s'", cmd), http.StatusBadRequest)
+		return
+	}
+	select {
+	case s.commands <- cmd:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "too many commands", http.StatusServiceUnavailable)
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e event) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, e.Data)
+	return err
+}
+
+func mustEvent(name string, data any) event {
+	js, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	return event{Name: name, Data: js}
+}
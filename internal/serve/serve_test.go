@@ -0,0 +1,213 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvents reads server-sent events from a response, and sends them to a channel.
+func readEvents(resp *http.Response) <-chan event {
+	events := make(chan event)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		e := event{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.Name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.Data = []byte(strings.TrimPrefix(line, "data: "))
+			case line == "":
+				events <- e
+				e = event{}
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan event) event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("event stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+	return event{}
+}
+
+func TestEvents(t *testing.T) {
+	s := NewServer(":0")
+	s.Reset(Layout{Run: 3, TimeSeries: []PlotLayout{{Title: "Stores", MaxRows: 2}}})
+	s.SetColumns(false, 0, []string{"Honey"})
+	for tick := range 3 {
+		s.AddRow(0, tick, []float64{float64(tick)})
+	}
+
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected content type text/event-stream, got %s", ct)
+	}
+	events := readEvents(resp)
+
+	// The full state is sent first, with rows trimmed to MaxRows.
+	layout := Layout{}
+	e := nextEvent(t, events)
+	if e.Name != "layout" {
+		t.Fatalf("expected layout event, got %s", e.Name)
+	}
+	if err := json.Unmarshal(e.Data, &layout); err != nil {
+		t.Fatal(err)
+	}
+	if layout.Run != 3 || len(layout.TimeSeries) != 1 || layout.TimeSeries[0].Columns[0] != "Honey" {
+		t.Errorf("unexpected layout %s", e.Data)
+	}
+	if e := nextEvent(t, events); e.Name != "state" {
+		t.Fatalf("expected state event, got %s", e.Name)
+	}
+	for _, tick := range []int{1, 2} {
+		e := nextEvent(t, events)
+		row := rowData{}
+		if err := json.Unmarshal(e.Data, &row); err != nil {
+			t.Fatal(err)
+		}
+		if e.Name != "row" || row.Tick != tick {
+			t.Errorf("expected row of tick %d, got %s %s", tick, e.Name, e.Data)
+		}
+	}
+
+	// Later updates are streamed.
+	if !s.HasClients() {
+		t.Fatal("expected a connected client")
+	}
+	s.UpdateState(State{Run: 3, Tick: 10, Paused: true})
+	e = nextEvent(t, events)
+	state := State{}
+	if err := json.Unmarshal(e.Data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "state" || state.Tick != 10 || !state.Paused {
+		t.Errorf("unexpected event %s %s", e.Name, e.Data)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.HasClients() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s.HasClients() {
+		t.Error("client was not removed after disconnecting")
+	}
+}
+
+func TestSlowClient(t *testing.T) {
+	s := NewServer(":0")
+	s.Reset(Layout{TimeSeries: []PlotLayout{{Title: "Stores"}}})
+
+	ch := make(chan event, 1)
+	s.clients[ch] = struct{}{}
+	s.AddRow(0, 0, []float64{1})
+	if !s.HasClients() {
+		t.Fatal("client was removed before its buffer was full")
+	}
+	s.AddRow(0, 1, []float64{2})
+	if s.HasClients() {
+		t.Fatal("expected a slow client to be removed")
+	}
+	if e := <-ch; e.Name != "row" {
+		t.Errorf("expected buffered row event, got %s", e.Name)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("expected the channel of a slow client to be closed")
+	}
+}
+
+func TestControl(t *testing.T) {
+	s := NewServer(":0")
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		cmd    string
+		status int
+	}{
+		{"play", http.MethodPost, CommandPlay, http.StatusNoContent},
+		{"pause", http.MethodPost, CommandPause, http.StatusNoContent},
+		{"step", http.MethodPost, CommandStep, http.StatusNoContent},
+		{"unknown", http.MethodPost, "rewind", http.StatusBadRequest},
+		{"get", http.MethodGet, CommandPlay, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+"/control/"+tt.cmd, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status != http.StatusNoContent {
+				return
+			}
+			select {
+			case cmd := <-s.Commands():
+				if cmd != tt.cmd {
+					t.Errorf("expected command %s, got %s", tt.cmd, cmd)
+				}
+			default:
+				t.Error("expected a command")
+			}
+		})
+	}
+}
+
+func TestControlFull(t *testing.T) {
+	s := NewServer(":0")
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	status := 0
+	for range cap(s.commands) + 1 {
+		resp, err := http.Post(server.URL+"/control/"+CommandStep, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d when commands are not consumed, got %d", http.StatusServiceUnavailable, status)
+	}
+}
@@ -0,0 +1,158 @@
+package serve
+
+import (
+	"bytes"
+	"image/png"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/internal/render"
+)
+
+// Controls is a UI system that applies play, pause and step commands from the browser,
+// and sends the simulation state to the dashboard.
+type Controls struct {
+	Server   *Server
+	Run      int  // Index of the run.
+	Paused   bool // Whether to start paused.
+	systems  *app.Systems
+	tick     ecs.Resource[resource.Tick]
+	stepping bool
+}
+
+// InitializeUI the system
+func (c *Controls) InitializeUI(w *ecs.World) {
+	c.systems = ecs.GetResource[app.Systems](w)
+	c.tick = ecs.NewResource[resource.Tick](w)
+	c.systems.Paused = c.Paused
+	c.stepping = false
+}
+
+// UpdateUI the system
+func (c *Controls) UpdateUI(w *ecs.World) {
+	for done := false; !done; {
+		select {
+		case cmd := <-c.Server.Commands():
+			c.apply(cmd)
+		default:
+			done = true
+		}
+	}
+
+	c.Server.UpdateState(State{
+		Run:    c.Run,
+		Tick:   c.tick.Get().Tick,
+		Paused: c.systems.Paused && !c.stepping,
+	})
+}
+
+func (c *Controls) apply(cmd string) {
+	switch cmd {
+	case CommandPlay:
+		c.systems.Paused = false
+		c.stepping = false
+	case CommandPause:
+		c.systems.Paused = true
+		c.stepping = false
+	case CommandStep:
+		c.systems.Paused = false
+		c.stepping = true
+	}
+}
+
+// PostUpdateUI the system
+func (c *Controls) PostUpdateUI(w *ecs.World) {}
+
+// FinalizeUI the system
+func (c *Controls) FinalizeUI(w *ecs.World) {
+	c.Server.UpdateState(State{
+		Run:    c.Run,
+		Tick:   c.tick.Get().Tick,
+		Paused: true,
+	})
+}
+
+// Initialize the system
+func (c *Controls) Initialize(w *ecs.World) {}
+
+// Update the system
+func (c *Controls) Update(w *ecs.World) {
+	if c.stepping {
+		c.systems.Paused = true
+		c.stepping = false
+	}
+}
+
+// Finalize the system
+func (c *Controls) Finalize(w *ecs.World) {}
+
+// View is a system that renders a [render.Drawer] to PNG images for the dashboard.
+// Images are only rendered while a browser is connected.
+// Errors terminate the run, and are available from Err afterwards.
+type View struct {
+	Server   *Server
+	Index    int           // Index of the view in the layout.
+	Drawer   render.Drawer // Drawer to render.
+	Interval int           // Interval between images, in ticks. Default: 1.
+	Width    int           // Image width in pixels. Default: 800.
+	Height   int           // Image height in pixels. Default: 600.
+	step     int64
+	err      error
+}
+
+// Initialize the system
+func (v *View) Initialize(w *ecs.World) {
+	if v.Interval <= 0 {
+		v.Interval = 1
+	}
+	if v.Width <= 0 {
+		v.Width = 800
+	}
+	if v.Height <= 0 {
+		v.Height = 600
+	}
+	v.Drawer.Initialize(w)
+	v.step = 0
+	v.err = nil
+}
+
+// Update the system
+func (v *View) Update(w *ecs.World) {
+	if v.err != nil {
+		return
+	}
+	v.Drawer.Update(w)
+
+	if v.step%int64(v.Interval) == 0 && v.Server.HasClients() {
+		img, err := v.Drawer.Render(w, v.Width, v.Height)
+		if err != nil {
+			v.fail(w, err)
+			return
+		}
+		buf := bytes.Buffer{}
+		if err := png.Encode(&buf, img); err != nil {
+			v.fail(w, err)
+			return
+		}
+		v.Server.UpdateView(v.Index, buf.Bytes())
+	}
+
+	v.step++
+}
+
+// Finalize the system
+func (v *View) Finalize(w *ecs.World) {}
+
+// Err returns the first error during the run, or nil.
+func (v *View) Err() error {
+	return v.err
+}
+
+// fail records the first error, and terminates the run.
+func (v *View) fail(w *ecs.World, err error) {
+	if v.err == nil {
+		v.err = err
+	}
+	ecs.GetResource[resource.Termination](w).Terminate = true
+}
@@ -0,0 +1,258 @@
+// Package snapshot provides saving and loading of the ECS world state, for warm starts of runs.
+package snapshot
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/events"
+)
+
+// skipResources are resources that are not part of a snapshot.
+// Tick and random state are stored separately.
+var skipResources = map[reflect.Type]bool{
+	reflect.TypeFor[app.Systems]():          true,
+	reflect.TypeFor[resource.Tick]():        true,
+	reflect.TypeFor[resource.Termination](): true,
+	reflect.TypeFor[resource.Rand]():        true,
+	reflect.TypeFor[events.Log]():           true,
+}
+
+// Snapshot of the world state, with entities, components and resources.
+//
+// Components and resources are identified by their full type name, and serialized as JSON.
+// Types with unexported fields must implement [json.Marshaler] and [json.Unmarshaler],
+// otherwise taking a snapshot fails.
+type Snapshot struct {
+	Tick       int64                        // Next tick to simulate.
+	Random     []byte                       // State of the app's random source.
+	Entities   ecs.EntityDump               // Entity state.
+	Components []map[string]json.RawMessage // Components of all alive entities, in the order of Entities.Alive.
+	Resources  map[string]json.RawMessage   // Resources, except those of the app.
+}
+
+// Take a snapshot of the world.
+// The tick is the next tick to simulate.
+func Take(w *ecs.World, tick int64) (*Snapshot, error) {
+	u := w.Unsafe()
+	s := Snapshot{
+		Tick:      tick,
+		Entities:  u.DumpEntities(),
+		Resources: map[string]json.RawMessage{},
+	}
+
+	rng := ecs.GetResource[resource.Rand](w)
+	m, ok := rng.Source.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("random source %T does not support binary marshaling", rng.Source)
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	s.Random = state
+
+	checked := map[reflect.Type]bool{}
+
+	query := ecs.NewUnsafeFilter(w).Query()
+	for query.Next() {
+		ids := query.IDs()
+		comps := make(map[string]json.RawMessage, ids.Len())
+		for i := range ids.Len() {
+			id := ids.Get(i)
+			info, _ := ecs.ComponentInfo(w, id)
+			if info.IsRelation {
+				query.Close()
+				return nil, fmt.Errorf("relation component %s is not supported in snapshots", typeName(info.Type))
+			}
+			if err := checkExported(info.Type, checked); err != nil {
+				query.Close()
+				return nil, fmt.Errorf("component %s can't be saved: %s", typeName(info.Type), err.Error())
+			}
+			js, err := json.Marshal(reflect.NewAt(info.Type, query.Get(id)).Interface())
+			if err != nil {
+				query.Close()
+				return nil, fmt.Errorf("serializing component %s: %s", typeName(info.Type), err.Error())
+			}
+			comps[typeName(info.Type)] = js
+		}
+		s.Components = append(s.Components, comps)
+	}
+	if len(s.Components) != len(s.Entities.Alive) {
+		return nil, fmt.Errorf("inconsistent entity state in snapshot")
+	}
+
+	res := w.Resources()
+	for _, id := range ecs.ResourceIDs(w) {
+		tp, _ := ecs.ResourceType(w, id)
+		if !res.Has(id) || skipResources[tp] {
+			continue
+		}
+		if err := checkExported(tp, checked); err != nil {
+			return nil, fmt.Errorf("resource %s can't be saved: %s", typeName(tp), err.Error())
+		}
+		js, err := json.Marshal(res.Get(id))
+		if err != nil {
+			return nil, fmt.Errorf("serializing resource %s: %s", typeName(tp), err.Error())
+		}
+		s.Resources[typeName(tp)] = js
+	}
+
+	return &s, nil
+}
+
+// Restore the world state from the snapshot.
+//
+// Resources are restored in place, so that pointers to them held by systems stay valid.
+// Component and resource types must be registered in the world.
+// Resources not contained in the snapshot are left unchanged.
+// Tick and random state are not restored.
+func (s *Snapshot) Restore(w *ecs.World) error {
+	components := map[string]reflect.Type{}
+	componentIDs := map[string]ecs.ID{}
+	for _, id := range ecs.ComponentIDs(w) {
+		if info, ok := ecs.ComponentInfo(w, id); ok {
+			components[typeName(info.Type)] = info.Type
+			componentIDs[typeName(info.Type)] = id
+		}
+	}
+	resourceIDs := map[string]ecs.ResID{}
+	for _, id := range ecs.ResourceIDs(w) {
+		if tp, ok := ecs.ResourceType(w, id); ok {
+			resourceIDs[typeName(tp)] = id
+		}
+	}
+	for name := range s.Resources {
+		if id, ok := resourceIDs[name]; !ok || !w.Resources().Has(id) {
+			return fmt.Errorf("resource %s of snapshot is not present in the world", name)
+		}
+	}
+
+	// Resetting the world removes all resources, so they are added again afterwards.
+	res := w.Resources()
+	present := map[ecs.ResID]any{}
+	for _, id := range resourceIDs {
+		if res.Has(id) {
+			present[id] = res.Get(id)
+		}
+	}
+	w.Reset()
+	for id, r := range present {
+		res.Add(id, r)
+	}
+
+	u := w.Unsafe()
+	u.LoadEntities(&s.Entities)
+	for i, idx := range s.Entities.Alive {
+		entity := s.Entities.Entities[idx]
+		comps := s.Components[i]
+		ids := make([]ecs.ID, 0, len(comps))
+		for name := range comps {
+			id, ok := componentIDs[name]
+			if !ok {
+				return fmt.Errorf("component %s of snapshot is not registered in the world", name)
+			}
+			ids = append(ids, id)
+		}
+		u.Add(entity, ids...)
+		for name, js := range comps {
+			value := reflect.NewAt(components[name], u.Get(entity, componentIDs[name])).Interface()
+			if err := json.Unmarshal(js, value); err != nil {
+				return fmt.Errorf("deserializing component %s: %s", name, err.Error())
+			}
+		}
+	}
+
+	for name, js := range s.Resources {
+		value := res.Get(resourceIDs[name])
+		reflect.ValueOf(value).Elem().SetZero()
+		if err := json.Unmarshal(js, value); err != nil {
+			return fmt.Errorf("deserializing resource %s: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// RestoreRandom restores the state of the app's random source.
+func (s *Snapshot) RestoreRandom(w *ecs.World) error {
+	if len(s.Random) == 0 {
+		return fmt.Errorf("snapshot contains no random state")
+	}
+	rng := ecs.GetResource[resource.Rand](w)
+	m, ok := rng.Source.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("random source %T does not support binary unmarshaling", rng.Source)
+	}
+	return m.UnmarshalBinary(s.Random)
+}
+
+// ReadFile reads a snapshot from a JSON file.
+func ReadFile(path string) (*Snapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := Snapshot{}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("reading snapshot '%s': %s", path, err.Error())
+	}
+	return &s, nil
+}
+
+// WriteFile writes the snapshot to a JSON file.
+func (s *Snapshot) WriteFile(path string) error {
+	js, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, js, 0644)
+}
+
+var jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+
+// checkExported returns an error if values of a type would lose state when serialized as JSON,
+// because it has unexported fields, or contains a type with unexported fields,
+// without implementing [json.Marshaler]. Checked types are recorded in the given map.
+func checkExported(tp reflect.Type, checked map[reflect.Type]bool) error {
+	if checked[tp] {
+		return nil
+	}
+	checked[tp] = true
+	if tp.Implements(jsonMarshaler) || reflect.PointerTo(tp).Implements(jsonMarshaler) {
+		return nil
+	}
+	switch tp.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return checkExported(tp.Elem(), checked)
+	case reflect.Struct:
+		for i := range tp.NumField() {
+			f := tp.Field(i)
+			if f.Tag.Get("json") == "-" {
+				continue
+			}
+			// Exported fields of embedded structs are promoted, even if the embedded type is unexported.
+			if !f.IsExported() && !(f.Anonymous && f.Type.Kind() == reflect.Struct) {
+				return fmt.Errorf("type %s has unexported field '%s', and does not implement json.Marshaler", tp.String(), f.Name)
+			}
+			if err := checkExported(f.Type, checked); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeName returns the full name of a type, including the package path.
+func typeName(tp reflect.Type) string {
+	return tp.PkgPath() + "." + tp.Name()
+}
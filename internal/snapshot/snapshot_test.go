@@ -0,0 +1,204 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+)
+
+type position struct{ X, Y float64 }
+type velocity struct{ Dx, Dy float64 }
+type stores struct {
+	Honey  float64
+	Counts []int
+}
+
+// hidden has unexported state.
+type hidden struct{ value int }
+
+// custom has unexported state, but implements JSON marshaling.
+type custom struct{ value int }
+
+func (c custom) MarshalJSON() ([]byte, error) { return json.Marshal(c.value) }
+func (c *custom) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &c.value)
+}
+
+type inner struct{ A int }
+type embedding struct {
+	inner
+	B int
+}
+type nested struct{ Values []hidden }
+type ignored struct {
+	A     int
+	cache int `json:"-"`
+}
+
+// source is a random source that doesn't support binary marshaling.
+type source struct{}
+
+func (s source) Uint64() uint64 { return 0 }
+
+func newWorld(src rand.Source) ecs.World {
+	w := ecs.NewWorld()
+	ecs.AddResource(&w, &resource.Rand{Source: src})
+	ecs.AddResource(&w, &stores{})
+	ecs.ComponentID[position](&w)
+	ecs.ComponentID[velocity](&w)
+	return w
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	w := newWorld(rand.NewPCG(1, 2))
+	ecs.GetResource[stores](&w).Honey = 42
+	ecs.GetResource[stores](&w).Counts = []int{1, 2, 3}
+	posMap := ecs.NewMap1[position](&w)
+	bothMap := ecs.NewMap2[position, velocity](&w)
+	e1 := posMap.NewEntity(&position{1, 2})
+	e2 := bothMap.NewEntity(&position{3, 4}, &velocity{5, 6})
+	e3 := posMap.NewEntity(&position{7, 8})
+	w.RemoveEntity(e1)
+	snap, err := Take(&w, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ecs.GetResource[resource.Rand](&w).Uint64()
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := snap.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Tick != 100 {
+		t.Errorf("expected tick 100, got %d", loaded.Tick)
+	}
+
+	w2 := newWorld(rand.NewPCG(3, 4))
+	if err := loaded.Restore(&w2); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.RestoreRandom(&w2); err != nil {
+		t.Fatal(err)
+	}
+
+	st := ecs.GetResource[stores](&w2)
+	if st.Honey != 42 || len(st.Counts) != 3 || st.Counts[2] != 3 {
+		t.Errorf("unexpected resource after restore: %+v", *st)
+	}
+	posMap2 := ecs.NewMap1[position](&w2)
+	velMap2 := ecs.NewMap1[velocity](&w2)
+	tests := []struct {
+		name   string
+		entity ecs.Entity
+		alive  bool
+		pos    position
+		vel    *velocity
+	}{
+		{"removed", e1, false, position{}, nil},
+		{"both", e2, true, position{3, 4}, &velocity{5, 6}},
+		{"position", e3, true, position{7, 8}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w2.Alive(tt.entity) != tt.alive {
+				t.Fatalf("expected alive %t", tt.alive)
+			}
+			if !tt.alive {
+				return
+			}
+			if p := posMap2.Get(tt.entity); *p != tt.pos {
+				t.Errorf("expected position %v, got %v", tt.pos, *p)
+			}
+			if tt.vel == nil {
+				if velMap2.HasAll(tt.entity) {
+					t.Errorf("unexpected velocity")
+				}
+			} else if v := velMap2.Get(tt.entity); *v != *tt.vel {
+				t.Errorf("expected velocity %v, got %v", *tt.vel, *v)
+			}
+		})
+	}
+	if v := ecs.GetResource[resource.Rand](&w2).Uint64(); v != expected {
+		t.Errorf("expected restored random state to produce %d, got %d", expected, v)
+	}
+}
+
+func TestCheckExported(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		err   string
+	}{
+		{"exported", position{}, ""},
+		{"unexported", hidden{}, "hidden"},
+		{"marshaler", custom{}, ""},
+		{"embedded", embedding{}, ""},
+		{"nested", nested{}, "hidden"},
+		{"ignored", ignored{}, ""},
+		{"pointer", &hidden{}, "hidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExported(reflect.TypeOf(tt.value), map[reflect.Type]bool{})
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err.Error())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error naming %s, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestTakeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(w *ecs.World)
+		src   rand.Source
+		err   string
+	}{
+		{"component", func(w *ecs.World) { ecs.NewMap1[hidden](w).NewEntity(&hidden{}) }, rand.NewPCG(1, 2), "component"},
+		{"resource", func(w *ecs.World) { ecs.AddResource(w, &hidden{}) }, rand.NewPCG(1, 2), "resource"},
+		{"random", func(w *ecs.World) {}, source{}, "random source"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWorld(tt.src)
+			tt.setup(&w)
+			_, err := Take(&w, 0)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error about %s, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRestoreRandomErrors(t *testing.T) {
+	w := newWorld(source{})
+	tests := []struct {
+		name string
+		snap Snapshot
+	}{
+		{"no state", Snapshot{}},
+		{"unsupported source", Snapshot{Random: []byte{1, 2, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.snap.RestoreRandom(&w); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Options for saving snapshots and starting runs from a snapshot.
+type Options struct {
+	From   *Snapshot // Snapshot to start runs from. Optional.
+	Random bool      // Whether to restore the random state of From, for a common random number history.
+	File   string    // File to save snapshots to. The run index is appended to the name. Optional.
+	Tick   int64     // Tick to save snapshots at.
+	SpinUp *SpinUp   // System for taking an in-memory snapshot, and terminating the run. Optional.
+}
+
+// RunFile returns the snapshot file for the run with the given index, relative to the given directory.
+func (o *Options) RunFile(dir string, index int) string {
+	ext := path.Ext(o.File)
+	return path.Join(dir, fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(o.File, ext), index, ext))
+}
+
+// failure records the first error of a system, and terminates the run on errors.
+type failure struct {
+	err error
+}
+
+// Err returns the first error of the system during the run, or nil.
+func (f *failure) Err() error {
+	return f.err
+}
+
+func (f *failure) fail(w *ecs.World, err error) {
+	if f.err == nil {
+		f.err = err
+	}
+	ecs.GetResource[resource.Termination](w).Terminate = true
+}
+
+// Loader is a system that restores the world state from a snapshot, at the start of the first update.
+//
+// Must be the first system, so that all other systems are initialized before,
+// and are updated for the first time after restoring the state.
+// Errors terminate the run, and are available from Err afterwards.
+type Loader struct {
+	failure
+	Snapshot *Snapshot                // The snapshot to restore.
+	Random   bool                     // Whether to restore the state of the random source.
+	Apply    func(w *ecs.World) error // Called after restoring, e.g. for applying parameters. Optional.
+	loaded   bool
+}
+
+// Initialize the system
+func (l *Loader) Initialize(w *ecs.World) {
+	l.loaded = false
+	l.err = nil
+}
+
+// Update the system
+func (l *Loader) Update(w *ecs.World) {
+	if l.loaded {
+		return
+	}
+	l.loaded = true
+
+	if err := l.Snapshot.Restore(w); err != nil {
+		l.fail(w, err)
+		return
+	}
+	if l.Random {
+		if err := l.Snapshot.RestoreRandom(w); err != nil {
+			l.fail(w, err)
+			return
+		}
+	}
+	if l.Apply != nil {
+		if err := l.Apply(w); err != nil {
+			l.fail(w, err)
+			return
+		}
+	}
+	ecs.GetResource[resource.Tick](w).Tick = l.Snapshot.Tick
+}
+
+// Finalize the system
+func (l *Loader) Finalize(w *ecs.World) {}
+
+// Saver is a system that saves a snapshot of the world state at the end of a tick.
+//
+// Must be the last system, so that the state after all updates of the tick is saved.
+// Errors terminate the run, and are available from Err afterwards.
+type Saver struct {
+	failure
+	File string // File to write the snapshot to.
+	Tick int64  // Tick to save the snapshot at.
+	tick ecs.Resource[resource.Tick]
+}
+
+// Initialize the system
+func (s *Saver) Initialize(w *ecs.World) {
+	s.tick = ecs.NewResource[resource.Tick](w)
+	s.err = nil
+}
+
+// Update the system
+func (s *Saver) Update(w *ecs.World) {
+	tick := s.tick.Get().Tick
+	if tick != s.Tick {
+		return
+	}
+	snap, err := Take(w, tick+1)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	if err := snap.WriteFile(s.File); err != nil {
+		s.fail(w, err)
+	}
+}
+
+// Finalize the system
+func (s *Saver) Finalize(w *ecs.World) {}
+
+// SpinUp is a system that takes an in-memory snapshot at the end of a tick, and terminates the run.
+// If the run terminates before that tick, the snapshot is taken from the final state.
+//
+// Must be the last system, so that the state after all updates of the tick is saved.
+// Errors terminate the run, and are available from Err afterwards.
+type SpinUp struct {
+	failure
+	Tick     int64     // Tick to take the snapshot at.
+	Snapshot *Snapshot // The snapshot taken, after the run.
+	tick     ecs.Resource[resource.Tick]
+	term     ecs.Resource[resource.Termination]
+}
+
+// Initialize the system
+func (s *SpinUp) Initialize(w *ecs.World) {
+	s.Snapshot = nil
+	s.err = nil
+	s.tick = ecs.NewResource[resource.Tick](w)
+	s.term = ecs.NewResource[resource.Termination](w)
+}
+
+// Update the system
+func (s *SpinUp) Update(w *ecs.World) {
+	tick := s.tick.Get().Tick
+	if tick != s.Tick {
+		return
+	}
+	snap, err := Take(w, tick+1)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.Snapshot = snap
+	s.term.Get().Terminate = true
+}
+
+// Finalize the system
+func (s *SpinUp) Finalize(w *ecs.World) {
+	if s.Snapshot != nil || s.err != nil {
+		return
+	}
+	// The tick was already incremented after the last update.
+	snap, err := Take(w, s.tick.Get().Tick)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	s.Snapshot = snap
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Difference describes the first difference between two tables.
+type Difference struct {
+	Run     string // Run of the difference.
+	Tick    string // Tick of the difference, if the table has a "Ticks" column.
+	Column  string // Column of the difference.
+	A, B    string // Differing values.
+	Message string // Description of structural differences.
+}
+
+func (d *Difference) String() string {
+	if d.Message != "" {
+		if d.Run != "" {
+			return fmt.Sprintf("run %s: %s", d.Run, d.Message)
+		}
+		return d.Message
+	}
+	if d.Tick != "" {
+		return fmt.Sprintf("run %s, tick %s, column '%s': %s != %s", d.Run, d.Tick, d.Column, d.A, d.B)
+	}
+	return fmt.Sprintf("run %s, column '%s': %s != %s", d.Run, d.Column, d.A, d.B)
+}
+
+// CompareTables compares two tables bit-for-bit, run by run.
+// Run order does not matter, as runs of parallel experiments finish in arbitrary order.
+// Columns in ignore are not compared.
+// Returns nil if the tables are identical.
+func CompareTables(a, b *CsvTable, ignore []string) *Difference {
+	if !slices.Equal(a.Header, b.Header) {
+		return &Difference{Message: fmt.Sprintf("headers differ: %v != %v", a.Header, b.Header)}
+	}
+	tickCol := a.Column("Ticks")
+
+	runsA, rowsA := a.RunRows()
+	runsB, rowsB := b.RunRows()
+	slices.Sort(runsA)
+	slices.Sort(runsB)
+	for _, run := range runsB {
+		if _, ok := rowsA[run]; !ok {
+			return &Difference{Run: run, Message: "run missing in first table"}
+		}
+	}
+
+	for _, run := range runsA {
+		ra := rowsA[run]
+		rb, ok := rowsB[run]
+		if !ok {
+			return &Difference{Run: run, Message: "run missing in second table"}
+		}
+		for i := range min(len(ra), len(rb)) {
+			for j, col := range a.Header {
+				if ra[i][j] == rb[i][j] || slices.Contains(ignore, col) {
+					continue
+				}
+				d := Difference{Run: run, Column: col, A: ra[i][j], B: rb[i][j]}
+				if tickCol >= 0 {
+					d.Tick = ra[i][tickCol]
+				}
+				return &d
+			}
+		}
+		if len(ra) != len(rb) {
+			return &Difference{Run: run, Message: fmt.Sprintf("number of rows differs: %d != %d", len(ra), len(rb))}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mlange-42/beecs-cli/events"
+)
+
+func TestCompareTables(t *testing.T) {
+	header := []string{"Run", "Ticks", "A", "Started"}
+	ref := CsvTable{Header: header, Rows: [][]string{
+		{"0", "0", "1", "100"},
+		{"0", "1", "2", "100"},
+		{"1", "0", "3", "100"},
+	}}
+	tests := []struct {
+		name   string
+		other  CsvTable
+		ignore []string
+		diff   *Difference
+	}{
+		{"identical", ref, nil, nil},
+		{"run order", CsvTable{Header: header, Rows: [][]string{
+			{"1", "0", "3", "100"},
+			{"0", "0", "1", "100"},
+			{"0", "1", "2", "100"},
+		}}, nil, nil},
+		{"value", CsvTable{Header: header, Rows: [][]string{
+			{"0", "0", "1", "100"},
+			{"0", "1", "2.5", "100"},
+			{"1", "0", "3", "100"},
+		}}, nil, &Difference{Run: "0", Tick: "1", Column: "A", A: "2", B: "2.5"}},
+		{"ignored", CsvTable{Header: header, Rows: [][]string{
+			{"0", "0", "1", "200"},
+			{"0", "1", "2", "200"},
+			{"1", "0", "3", "200"},
+		}}, []string{"Started"}, nil},
+		{"header", CsvTable{Header: []string{"Run", "Ticks", "B", "Started"}, Rows: ref.Rows}, nil,
+			&Difference{Message: "headers differ: [Run Ticks A Started] != [Run Ticks B Started]"}},
+		{"missing run", CsvTable{Header: header, Rows: ref.Rows[:2]}, nil,
+			&Difference{Run: "1", Message: "run missing in second table"}},
+		{"extra run", CsvTable{Header: header, Rows: append(append([][]string{}, ref.Rows...), []string{"2", "0", "1", "100"})}, nil,
+			&Difference{Run: "2", Message: "run missing in first table"}},
+		{"rows", CsvTable{Header: header, Rows: [][]string{
+			{"0", "0", "1", "100"},
+			{"1", "0", "3", "100"},
+		}}, nil, &Difference{Run: "0", Message: "number of rows differs: 2 != 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := CompareTables(&ref, &tt.other, tt.ignore)
+			if tt.diff == nil {
+				if diff != nil {
+					t.Errorf("expected no difference, got %s", diff.String())
+				}
+				return
+			}
+			if diff == nil {
+				t.Fatalf("expected difference %s, got none", tt.diff.String())
+			}
+			if *diff != *tt.diff {
+				t.Errorf("expected difference %s, got %s", tt.diff.String(), diff.String())
+			}
+		})
+	}
+}
+
+func TestReadEventLog(t *testing.T) {
+	evts := []events.Event{
+		{Run: 0, Tick: 12, Type: events.Swarming, Payload: map[string]any{"Adults": 20000.0, "Tick": 12.0}},
+		{Run: 1, Tick: 40, Type: events.Extinction},
+	}
+	for _, ext := range []string{".csv", ".jsonl"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events"+ext)
+			w, err := NewEventWriter(path, ",")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(evts); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			table, err := ReadEventLog(path, ",")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := CsvTable{
+				Header: []string{"Run", "Tick", "Type", "Payload"},
+				Rows: [][]string{
+					{"0", "12", events.Swarming, `{"Adults":20000,"Tick":12}`},
+					{"1", "40", events.Extinction, ""},
+				},
+			}
+			if diff := CompareTables(&table, &expected, nil); diff != nil {
+				t.Errorf("unexpected event log content: %s", diff.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package util
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -42,18 +44,31 @@ func NewCsvWriter(files []string, sep string) (CsvWriter, error) {
 	}, nil
 }
 
-func (w *CsvWriter) Write(tables *Tables) error {
-	if !w.initialized {
-		for i := range tables.Headers {
-			if i == 0 && w.files[i] == nil {
-				continue
-			}
-			_, err := fmt.Fprintln(w.files[i], strings.Join(tables.Headers[i], w.sep))
-			if err != nil {
-				return err
-			}
+// NewTempCsvWriter creates a writer to temporary files for buffering tables on disk.
+// The first table (parameters) is not written, and no headers are written.
+// Files are removed by [CsvWriter.Append] or [CsvWriter.Remove].
+func NewTempCsvWriter(tables int, sep string) (CsvWriter, error) {
+	w := CsvWriter{
+		files:       []*os.File{nil},
+		sep:         sep,
+		initialized: true,
+	}
+
+	for i := 1; i < tables; i++ {
+		ff, err := os.CreateTemp("", "beecs-*.csv")
+		if err != nil {
+			w.Remove()
+			return CsvWriter{}, err
 		}
-		w.initialized = true
+		w.files = append(w.files, ff)
+	}
+
+	return w, nil
+}
+
+func (w *CsvWriter) Write(tables *Tables) error {
+	if err := w.writeHeaders(tables); err != nil {
+		return err
 	}
 
 	for i := range tables.Data {
@@ -80,6 +95,85 @@ func (w *CsvWriter) Write(tables *Tables) error {
 	return nil
 }
 
+// Append writes the given tables, followed by the content of a temporary writer.
+// The temporary writer must be closed before, and its files are removed afterwards.
+func (w *CsvWriter) Append(tables *Tables, temp *CsvWriter) error {
+	if err := w.Write(tables); err != nil {
+		return err
+	}
+	for i, f := range temp.files {
+		if i == 0 && f == nil {
+			continue
+		}
+		if err := appendFile(w.files[i], f.Name()); err != nil {
+			temp.Remove()
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows reads the rows of a closed temporary writer, and calls fn for each row, table by table.
+// The files are kept, so that the rows can be read repeatedly.
+func (w *CsvWriter) Rows(fn func(table int, row []float64)) error {
+	for i, f := range w.files {
+		if i == 0 && f == nil {
+			continue
+		}
+		if err := readRows(f.Name(), w.sep, func(row []float64) { fn(i, row) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRows reads the rows of a CSV file without header, and calls fn for each row.
+func readRows(path string, sep string, fn func(row []float64)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			values := strings.Split(line, sep)
+			row := make([]float64, len(values))
+			for i, v := range values {
+				if row[i], err = strconv.ParseFloat(v, 64); err != nil {
+					return fmt.Errorf("invalid value in '%s': %s", path, err.Error())
+				}
+			}
+			fn(row)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+func (w *CsvWriter) writeHeaders(tables *Tables) error {
+	if w.initialized {
+		return nil
+	}
+	for i := range tables.Headers {
+		if i == 0 && w.files[i] == nil {
+			continue
+		}
+		_, err := fmt.Fprintln(w.files[i], strings.Join(tables.Headers[i], w.sep))
+		if err != nil {
+			return err
+		}
+	}
+	w.initialized = true
+	return nil
+}
+
 func (w *CsvWriter) Close() error {
 	for i, f := range w.files {
 		if i == 0 && f == nil {
@@ -91,3 +185,92 @@ func (w *CsvWriter) Close() error {
 	}
 	return nil
 }
+
+// Remove closes the writer and removes its files. For discarding temporary writers.
+func (w *CsvWriter) Remove() error {
+	var err error
+	for i, f := range w.files {
+		if i == 0 && f == nil {
+			continue
+		}
+		f.Close()
+		if e := os.Remove(f.Name()); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// appendFile appends the content of a file to another file, and removes it.
+// The file is removed also if appending fails.
+func appendFile(dst *os.File, path string) error {
+	defer os.Remove(path)
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// CsvTable is a table read from a CSV file.
+type CsvTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// ReadCsv reads a CSV file written by a [CsvWriter].
+func ReadCsv(path string, sep string) (CsvTable, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return CsvTable{}, err
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return CsvTable{}, fmt.Errorf("empty CSV file '%s'", path)
+	}
+
+	table := CsvTable{
+		Header: strings.Split(lines[0], sep),
+		Rows:   make([][]string, 0, len(lines)-1),
+	}
+	for i, line := range lines[1:] {
+		row := strings.Split(line, sep)
+		if len(row) != len(table.Header) {
+			return CsvTable{}, fmt.Errorf("row %d in '%s' has %d columns, expected %d", i+1, path, len(row), len(table.Header))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// Column returns the index of a column, or -1 if not present.
+func (t *CsvTable) Column(name string) int {
+	for i, h := range t.Header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// RunRows groups the table's rows by the "Run" column.
+// Returns the run labels in the order of first appearance, and the rows per run.
+// If there is no "Run" column, all rows are assigned to run "".
+func (t *CsvTable) RunRows() ([]string, map[string][][]string) {
+	col := t.Column("Run")
+	runs := []string{}
+	rows := map[string][][]string{}
+	for _, row := range t.Rows {
+		run := ""
+		if col >= 0 {
+			run = row[col]
+		}
+		if _, ok := rows[run]; !ok {
+			runs = append(runs, run)
+		}
+		rows[run] = append(rows[run], row)
+	}
+	return runs, rows
+}
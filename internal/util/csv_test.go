@@ -0,0 +1,182 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/beecs-cli/events"
+)
+
+func TestCsvWriterAppend(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"", filepath.Join(dir, "table.csv")}
+	writer, err := NewCsvWriter(files, ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp, err := NewTempCsvWriter(2, ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := Tables{
+		Headers: [][]string{{"Run"}, {"Run", "Ticks", "A"}},
+		Data:    [][][]float64{{{0}}, {{0, 0, 1.5}, {0, 1, 2}}},
+	}
+	if err := temp.Write(&tables); err != nil {
+		t.Fatal(err)
+	}
+	tempFile := temp.files[1].Name()
+	if err := temp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Append(&Tables{Headers: tables.Headers, Data: make([][][]float64, 2)}, &temp); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+		t.Errorf("temporary file was not removed")
+	}
+	content, err := os.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Run,Ticks,A\n0,0,1.5\n0,1,2\n"
+	if string(content) != expected {
+		t.Errorf("expected content %q, got %q", expected, string(content))
+	}
+}
+
+func TestTempCsvWriterRemove(t *testing.T) {
+	temp, err := NewTempCsvWriter(3, ",")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{temp.files[1].Name(), temp.files[2].Name()}
+	if err := temp.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("temporary file %s was not removed", name)
+		}
+	}
+}
+
+func TestTempCsvWriterRows(t *testing.T) {
+	temp, err := NewTempCsvWriter(3, ";")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer temp.Remove()
+	tables := Tables{Data: [][][]float64{nil, {{0, 0, 1.5}, {0, 1, 0.1}}, {{0, 1, 2}}}}
+	if err := temp.Write(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if err := temp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows can be read repeatedly.
+	for range 2 {
+		read := [][][]float64{nil, nil, nil}
+		err := temp.Rows(func(table int, row []float64) {
+			read[table] = append(read[table], row)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < len(read); i++ {
+			if !slices.EqualFunc(read[i], tables.Data[i], slices.Equal) {
+				t.Errorf("expected rows %v in table %d, got %v", tables.Data[i], i, read[i])
+			}
+		}
+	}
+}
+
+func TestTempEventWriterEvents(t *testing.T) {
+	temp, err := NewTempEventWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer temp.Remove()
+	evts := []events.Event{
+		{Run: 1, Tick: 10, Type: events.Extinction},
+		{Run: 1, Tick: 12, Type: events.PatchDepletion, Payload: map[string]any{"Patch": 3.0}},
+	}
+	if err := temp.Write(evts); err != nil {
+		t.Fatal(err)
+	}
+	if err := temp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := []events.Event{}
+	if err := temp.Events(func(e events.Event) { read = append(read, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(evts) {
+		t.Fatalf("expected %d events, got %v", len(evts), read)
+	}
+	for i, e := range evts {
+		if read[i].Tick != e.Tick || read[i].Type != e.Type || len(read[i].Payload) != len(e.Payload) {
+			t.Errorf("expected event %v, got %v", e, read[i])
+		}
+	}
+	if read[1].Payload["Patch"] != 3.0 {
+		t.Errorf("expected payload %v, got %v", evts[1].Payload, read[1].Payload)
+	}
+}
+
+func TestEventWriterAppend(t *testing.T) {
+	for _, ext := range []string{".csv", ".jsonl"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events"+ext)
+			writer, err := NewEventWriter(path, ";")
+			if err != nil {
+				t.Fatal(err)
+			}
+			temp, err := writer.Temp()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = temp.Write([]events.Event{{Run: 1, Tick: 10, Type: events.Extinction}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := temp.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Append(temp); err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(temp.file.Name()); !os.IsNotExist(err) {
+				t.Errorf("temporary file was not removed")
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			expected := 1
+			if ext == ".csv" {
+				expected = 2
+			}
+			if len(lines) != expected {
+				t.Fatalf("expected %d lines, got %q", expected, lines)
+			}
+			if !strings.Contains(lines[len(lines)-1], events.Extinction) {
+				t.Errorf("expected event in last line, got %q", lines[len(lines)-1])
+			}
+		})
+	}
+}
@@ -28,12 +28,13 @@ func ParametersFromFile(path string, params *params.DefaultParams) error {
 type ExperimentJs struct {
 	Seed       uint32
 	Parameters []experiment.ParameterVariation
+	Fork       *ForkDef `json:",omitempty"`
 }
 
-func ExperimentFromFile(path string, runs int, seed int) (experiment.Experiment, *rand.Rand, error) {
+func ExperimentDefFromFile(path string) (ExperimentJs, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return experiment.Experiment{}, nil, err
+		return ExperimentJs{}, err
 	}
 	defer file.Close()
 
@@ -42,17 +43,23 @@ func ExperimentFromFile(path string, runs int, seed int) (experiment.Experiment,
 	decoder := json.NewDecoder(file)
 	decoder.DisallowUnknownFields()
 	if err = decoder.Decode(&expJs); err != nil {
-		return experiment.Experiment{}, nil, err
+		return ExperimentJs{}, err
 	}
 
-	if seed == 0 {
-		seed = int(expJs.Seed)
-	} else if seed < 0 {
-		seed = int(rand.Uint32())
+	return expJs, nil
+}
+
+// Build creates an experiment from the definition.
+// The given super-seed is used for generating the seeds of individual runs.
+func (e *ExperimentJs) Build(runs int, superSeed uint64) (experiment.Experiment, *rand.Rand, error) {
+	if e.Fork != nil {
+		if err := e.Fork.Validate(); err != nil {
+			return experiment.Experiment{}, nil, err
+		}
 	}
-	rng := rand.New(rand.NewPCG(0, uint64(seed)))
+	rng := rand.New(rand.NewPCG(0, superSeed))
 
-	exp, err := experiment.New(expJs.Parameters, rng, runs)
+	exp, err := experiment.New(e.Parameters, rng, runs)
 	if err != nil {
 		return experiment.Experiment{}, nil, err
 	}
@@ -83,6 +90,14 @@ func ObserversDefFromFile(path string) (ObserversDef, error) {
 }
 
 func SystemsFromFile(path string) ([]app.System, error) {
+	names, err := SystemNamesFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return SystemsFromNames(names)
+}
+
+func SystemNamesFromFile(path string) ([]string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -97,8 +112,12 @@ func SystemsFromFile(path string) ([]app.System, error) {
 		return nil, err
 	}
 
+	return sysStr, nil
+}
+
+func SystemsFromNames(names []string) ([]app.System, error) {
 	sys := []app.System{}
-	for _, tpName := range sysStr {
+	for _, tpName := range names {
 		tp, ok := registry.GetSystem(tpName)
 		if !ok {
 			return nil, fmt.Errorf("system type '%s' is not registered", tpName)
@@ -113,3 +132,21 @@ func SystemsFromFile(path string) ([]app.System, error) {
 
 	return sys, nil
 }
+
+func RegressionDefFromFile(path string) (RegressionDef, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return RegressionDef{}, err
+	}
+	defer file.Close()
+
+	var def RegressionDef
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&def); err != nil {
+		return def, err
+	}
+
+	return def, nil
+}
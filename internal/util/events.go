@@ -0,0 +1,225 @@
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mlange-42/beecs-cli/events"
+)
+
+// EventLogDef defines the event log output.
+type EventLogDef struct {
+	File              string   // Output file, CSV or JSON lines (extension .jsonl).
+	Events            []string // Built-in event types to detect. Default: all.
+	SwarmingThreshold float64  // Number of adult bees for Swarming events. Swarming is not detected if zero.
+}
+
+// CreateEventWriter creates a writer for the event log file, relative to the given directory.
+// Returns nil if there is no event log file.
+func (obs *ObserversDef) CreateEventWriter(dir string) (*EventWriter, error) {
+	if obs.EventLog.File == "" {
+		return nil, nil
+	}
+	return NewEventWriter(filepath.Join(dir, obs.EventLog.File), obs.CsvSeparator)
+}
+
+// EventWriter writes events to a CSV or JSON lines file.
+type EventWriter struct {
+	file *os.File
+	csv  *csv.Writer
+	sep  rune
+}
+
+// NewEventWriter creates a writer for the given file.
+// The format is JSON lines for files with extension .jsonl, and CSV with the given separator otherwise.
+func NewEventWriter(path string, sep string) (*EventWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &EventWriter{file: file}
+	if strings.ToLower(filepath.Ext(path)) == ".jsonl" {
+		return w, nil
+	}
+
+	w.sep = ','
+	if r, _ := utf8.DecodeRuneInString(sep); r != utf8.RuneError {
+		w.sep = r
+	}
+	w.csv = newCsvEventWriter(file, w.sep)
+	if err := w.csv.Write([]string{"Run", "Tick", "Type", "Payload"}); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Temp creates a writer to a temporary file, in the same format but without a header,
+// for buffering the events of a run on disk.
+// The file is removed by [EventWriter.Append] or [EventWriter.Remove].
+func (w *EventWriter) Temp() (*EventWriter, error) {
+	file, err := os.CreateTemp("", "beecs-*.events")
+	if err != nil {
+		return nil, err
+	}
+	temp := &EventWriter{file: file, sep: w.sep}
+	if w.csv != nil {
+		temp.csv = newCsvEventWriter(file, w.sep)
+	}
+	return temp, nil
+}
+
+// NewTempEventWriter creates a writer to a temporary JSON lines file,
+// for buffering events on disk and reading them with [EventWriter.Events].
+// The file is removed by [EventWriter.Remove].
+func NewTempEventWriter() (*EventWriter, error) {
+	file, err := os.CreateTemp("", "beecs-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	return &EventWriter{file: file}, nil
+}
+
+// Events reads the events of a closed JSON lines writer, and calls fn for each event.
+// The file is kept, so that the events can be read repeatedly.
+func (w *EventWriter) Events(fn func(e events.Event)) error {
+	if w.csv != nil {
+		return fmt.Errorf("can't read events from CSV file '%s'", w.file.Name())
+	}
+	file, err := os.Open(w.file.Name())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		e := events.Event{}
+		if err := decoder.Decode(&e); err != nil {
+			return err
+		}
+		fn(e)
+	}
+	return nil
+}
+
+// Append writes the content of a temporary writer.
+// The temporary writer must be closed before, and its file is removed afterwards.
+func (w *EventWriter) Append(temp *EventWriter) error {
+	if err := w.flush(); err != nil {
+		temp.Remove()
+		return err
+	}
+	return appendFile(w.file, temp.file.Name())
+}
+
+// Remove closes the writer and removes its file. For discarding temporary writers.
+func (w *EventWriter) Remove() error {
+	w.file.Close()
+	return os.Remove(w.file.Name())
+}
+
+func newCsvEventWriter(file *os.File, sep rune) *csv.Writer {
+	w := csv.NewWriter(file)
+	w.Comma = sep
+	return w
+}
+
+// Write the given events.
+func (w *EventWriter) Write(evts []events.Event) error {
+	for _, e := range evts {
+		if w.csv == nil {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w.file, string(line)); err != nil {
+				return err
+			}
+			continue
+		}
+		payload := ""
+		if len(e.Payload) > 0 {
+			p, err := json.Marshal(e.Payload)
+			if err != nil {
+				return err
+			}
+			payload = string(p)
+		}
+		err := w.csv.Write([]string{fmt.Sprint(e.Run), fmt.Sprint(e.Tick), e.Type, payload})
+		if err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// Close the writer.
+func (w *EventWriter) Close() error {
+	if err := w.flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// flush buffered CSV output.
+func (w *EventWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// ReadEventLog reads an event log file written by an [EventWriter], as a table of strings.
+// Columns are Run, Tick, Type and Payload, with the payload as JSON.
+func ReadEventLog(path string, sep string) (CsvTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return CsvTable{}, err
+	}
+	defer file.Close()
+
+	table := CsvTable{Header: []string{"Run", "Tick", "Type", "Payload"}}
+	if strings.ToLower(filepath.Ext(path)) == ".jsonl" {
+		decoder := json.NewDecoder(file)
+		for decoder.More() {
+			var e events.Event
+			if err := decoder.Decode(&e); err != nil {
+				return CsvTable{}, fmt.Errorf("reading event log '%s': %s", path, err.Error())
+			}
+			payload := ""
+			if len(e.Payload) > 0 {
+				p, err := json.Marshal(e.Payload)
+				if err != nil {
+					return CsvTable{}, err
+				}
+				payload = string(p)
+			}
+			table.Rows = append(table.Rows, []string{fmt.Sprint(e.Run), fmt.Sprint(e.Tick), e.Type, payload})
+		}
+		return table, nil
+	}
+
+	reader := csv.NewReader(file)
+	if r, _ := utf8.DecodeRuneInString(sep); r != utf8.RuneError {
+		reader.Comma = r
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return CsvTable{}, fmt.Errorf("reading event log '%s': %s", path, err.Error())
+	}
+	if len(rows) == 0 {
+		return CsvTable{}, fmt.Errorf("empty event log '%s'", path)
+	}
+	table.Rows = rows[1:]
+	return table, nil
+}
@@ -0,0 +1,99 @@
+package util
+
+import "fmt"
+
+// TableFilter selects the columns and ticks of a table that are recorded.
+//
+// Rows of tables with Final set are always recorded, as they are written only once.
+type TableFilter struct {
+	final     bool
+	columns   []string
+	startTick int
+	endTick   int // Last tick to record, or -1 for no limit.
+	atTicks   map[int]bool
+	indices   []int
+}
+
+// NewTableFilter creates a filter from a table definition.
+// Returns an error if EndTick is before StartTick, or if ticks in AtTicks are not on the table's update interval.
+func NewTableFilter(t *TableDef) (TableFilter, error) {
+	endTick := -1
+	if t.EndTick != nil {
+		endTick = *t.EndTick
+		if !t.Final && endTick < t.StartTick {
+			return TableFilter{}, fmt.Errorf("EndTick %d of table '%s' is before its StartTick %d", endTick, t.File, t.StartTick)
+		}
+	}
+	interval := max(t.UpdateInterval, 1)
+	var atTicks map[int]bool
+	if len(t.AtTicks) > 0 {
+		atTicks = make(map[int]bool, len(t.AtTicks))
+		for _, tick := range t.AtTicks {
+			if !t.Final && tick%interval != 0 {
+				return TableFilter{}, fmt.Errorf("tick %d in AtTicks of table '%s' is not a multiple of its UpdateInterval %d", tick, t.File, interval)
+			}
+			atTicks[tick] = true
+		}
+	}
+	return TableFilter{
+		final:     t.Final,
+		columns:   t.Columns,
+		startTick: t.StartTick,
+		endTick:   endTick,
+		atTicks:   atTicks,
+	}, nil
+}
+
+// Header returns the selected columns of the observer's header.
+// Must be called before [TableFilter.Row].
+func (f *TableFilter) Header(header []string) ([]string, error) {
+	if len(f.columns) == 0 {
+		f.indices = nil
+		return header, nil
+	}
+
+	f.indices = make([]int, len(f.columns))
+	for i, col := range f.columns {
+		idx := -1
+		for j, h := range header {
+			if h == col {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("column '%s' not found in observer header %v", col, header)
+		}
+		f.indices[i] = idx
+	}
+	return f.columns, nil
+}
+
+// Row returns the selected columns of a data row.
+func (f *TableFilter) Row(row []float64) []float64 {
+	if f.indices == nil {
+		return row
+	}
+	sel := make([]float64, len(f.indices))
+	for i, idx := range f.indices {
+		sel[i] = row[idx]
+	}
+	return sel
+}
+
+// Accept returns whether data of the given tick should be recorded.
+func (f *TableFilter) Accept(tick int) bool {
+	if f.final {
+		return true
+	}
+	if tick < f.startTick {
+		return false
+	}
+	if f.endTick >= 0 && tick > f.endTick {
+		return false
+	}
+	if f.atTicks != nil && !f.atTicks[tick] {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTableFilterAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    TableDef
+		ticks  []int
+		accept []int
+	}{
+		{"all", TableDef{}, []int{0, 1, 2, 3}, []int{0, 1, 2, 3}},
+		{"start", TableDef{StartTick: 2}, []int{0, 1, 2, 3}, []int{2, 3}},
+		{"end", TableDef{EndTick: intPtr(1)}, []int{0, 1, 2, 3}, []int{0, 1}},
+		{"end zero", TableDef{EndTick: intPtr(0)}, []int{0, 1, 2, 3}, []int{0}},
+		{"window", TableDef{StartTick: 1, EndTick: intPtr(2)}, []int{0, 1, 2, 3}, []int{1, 2}},
+		{"at", TableDef{AtTicks: []int{0, 3}}, []int{0, 1, 2, 3}, []int{0, 3}},
+		{"at interval", TableDef{UpdateInterval: 5, AtTicks: []int{10}}, []int{0, 5, 10, 15}, []int{10}},
+		{"final", TableDef{Final: true, EndTick: intPtr(10), AtTicks: []int{3}}, []int{365}, []int{365}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTableFilter(&tt.def)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accepted := []int{}
+			for _, tick := range tt.ticks {
+				if f.Accept(tick) {
+					accepted = append(accepted, tick)
+				}
+			}
+			if !slices.Equal(accepted, tt.accept) {
+				t.Errorf("expected ticks %v, got %v", tt.accept, accepted)
+			}
+		})
+	}
+}
+
+func TestTableFilterAtTicksInterval(t *testing.T) {
+	_, err := NewTableFilter(&TableDef{UpdateInterval: 7, AtTicks: []int{14, 15}})
+	if err == nil {
+		t.Error("expected error for tick not on the update interval")
+	}
+}
+
+func TestTableFilterEndTick(t *testing.T) {
+	_, err := NewTableFilter(&TableDef{StartTick: 10, EndTick: intPtr(5)})
+	if err == nil {
+		t.Error("expected error for EndTick before StartTick")
+	}
+}
+
+func TestTableFilterColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		header  []string
+		row     []float64
+		expect  []float64
+		err     bool
+	}{
+		{"all", nil, []string{"A", "B", "C"}, []float64{1, 2, 3}, []float64{1, 2, 3}, false},
+		{"select", []string{"C", "A"}, []string{"A", "B", "C"}, []float64{1, 2, 3}, []float64{3, 1}, false},
+		{"missing", []string{"D"}, []string{"A", "B", "C"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTableFilter(&TableDef{Columns: tt.columns})
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, err := f.Header(tt.header)
+			if tt.err {
+				if err == nil {
+					t.Error("expected error for missing column")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(header) != len(tt.expect) {
+				t.Errorf("expected %d columns, got %v", len(tt.expect), header)
+			}
+			if row := f.Row(tt.row); !slices.Equal(row, tt.expect) {
+				t.Errorf("expected row %v, got %v", tt.expect, row)
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
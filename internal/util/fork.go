@@ -0,0 +1,49 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/mlange-42/beecs/experiment"
+)
+
+// ForkDef defines the forking of runs into treatments, after a shared spin-up.
+//
+// Each run of the experiment is simulated up to Tick once, and then continued separately for each treatment.
+// Treatments share the state and the random number history of the spin-up.
+type ForkDef struct {
+	Tick       int64          // Tick at which treatments start, with their parameters applied. Must be at least 1.
+	Treatments []TreatmentDef // Treatments each run is forked into.
+}
+
+// TreatmentDef defines a treatment of forked runs.
+type TreatmentDef struct {
+	Name       string                      // Name of the treatment.
+	Parameters []experiment.ParameterValue // Parameter values applied at the fork tick. Optional.
+}
+
+// Validate the fork definition.
+func (f *ForkDef) Validate() error {
+	if f.Tick < 1 {
+		return fmt.Errorf("fork tick must be at least 1, got %d", f.Tick)
+	}
+	if len(f.Treatments) == 0 {
+		return fmt.Errorf("fork requires at least one treatment")
+	}
+	for i, t := range f.Treatments {
+		for _, par := range t.Parameters {
+			if par.Parameter == "" {
+				return fmt.Errorf("missing parameter name in fork treatment %d '%s'", i, t.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Forks returns the number of runs each run of the experiment is forked into.
+// Returns 1 if the definition is nil.
+func (f *ForkDef) Forks() int {
+	if f == nil {
+		return 1
+	}
+	return len(f.Treatments)
+}
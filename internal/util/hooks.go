@@ -0,0 +1,58 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mlange-42/beecs-cli/registry"
+)
+
+// HookDef defines a lifecycle hook, called at a certain point of each run.
+type HookDef struct {
+	Hook   string             // Registered type name of the hook.
+	At     registry.HookPoint // Point at which the hook is called.
+	Config json.RawMessage    // JSON configuration of the hook. Optional.
+}
+
+// Hooks holds the hooks of a run, by hook point.
+type Hooks map[registry.HookPoint][]registry.Hook
+
+// HooksDefFromFile reads hook definitions from a JSON file.
+func HooksDefFromFile(path string) ([]HookDef, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var hooks []HookDef
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// CreateHooks creates new hooks from their definitions.
+func CreateHooks(defs []HookDef) (Hooks, error) {
+	hooks := Hooks{}
+	for _, def := range defs {
+		if !slices.Contains(registry.HookPoints, def.At) {
+			return nil, fmt.Errorf("unknown hook point '%s' for hook '%s'", def.At, def.Hook)
+		}
+		tp, ok := registry.GetHook(def.Hook)
+		if !ok {
+			return nil, fmt.Errorf("hook type '%s' is not registered", def.Hook)
+		}
+		hookVal, err := decodeConfig(tp, def.Config)
+		if err != nil {
+			return nil, err
+		}
+		hooks[def.At] = append(hooks[def.At], hookVal.(registry.Hook))
+	}
+	return hooks, nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/registry"
+)
+
+// testHook is a hook for tests.
+type testHook struct {
+	Name string
+}
+
+func (h *testHook) Run(run int, seed int, world *ecs.World) error {
+	return nil
+}
+
+func init() {
+	registry.RegisterHook[testHook]()
+}
+
+func TestCreateHooks(t *testing.T) {
+	hook := func(at registry.HookPoint, name string) HookDef {
+		return HookDef{Hook: "util.testHook", At: at, Config: json.RawMessage(`{"Name": "` + name + `"}`)}
+	}
+	tests := []struct {
+		name     string
+		defs     []HookDef
+		expected map[registry.HookPoint][]string
+		err      string
+	}{
+		{"empty", nil, map[registry.HookPoint][]string{}, ""},
+		{"ordered", []HookDef{hook(registry.BeforeRun, "A"), hook(registry.AfterSetup, "B"), hook(registry.BeforeRun, "C")},
+			map[registry.HookPoint][]string{registry.AfterSetup: {"B"}, registry.BeforeRun: {"A", "C"}}, ""},
+		{"no config", []HookDef{{Hook: "util.testHook", At: registry.AfterFinish}},
+			map[registry.HookPoint][]string{registry.AfterFinish: {""}}, ""},
+		{"unknown point", []HookDef{hook("AfterEverything", "A")}, nil, "unknown hook point"},
+		{"missing point", []HookDef{{Hook: "util.testHook"}}, nil, "unknown hook point"},
+		{"unknown type", []HookDef{{Hook: "util.otherHook", At: registry.AfterSetup}}, nil, "not registered"},
+		{"unknown field", []HookDef{{Hook: "util.testHook", At: registry.AfterSetup, Config: json.RawMessage(`{"Label": "A"}`)}},
+			nil, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := CreateHooks(tt.defs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error about %s, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hooks) != len(tt.expected) {
+				t.Fatalf("expected hooks at %d points, got %d", len(tt.expected), len(hooks))
+			}
+			for at, names := range tt.expected {
+				if len(hooks[at]) != len(names) {
+					t.Fatalf("expected %d hooks at %s, got %d", len(names), at, len(hooks[at]))
+				}
+				for i, name := range names {
+					if h := hooks[at][i].(*testHook); h.Name != name {
+						t.Errorf("expected hook %s at position %d of %s, got %s", name, i, at, h.Name)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlange-42/beecs/experiment"
+)
+
+const (
+	cliModule   = "github.com/mlange-42/beecs-cli"
+	beecsModule = "github.com/mlange-42/beecs"
+)
+
+// Manifest records everything required to reproduce an experiment.
+type Manifest struct {
+	Versions         Versions                    // Versions of the executable and relevant modules.
+	CommandLine      []string                    // Full command line.
+	WorkingDirectory string                      // Absolute working directory.
+	OutputDirectory  string                      // Absolute output directory.
+	Parameters       json.RawMessage             // Resolved parameters, including overwrites.
+	Overwrite        []experiment.ParameterValue // Parameter overwrites given via the command line.
+	Experiment       ExperimentJs                // Experiment definition.
+	Runs             int                         // Runs per parameter set.
+	SuperSeed        uint64                      // Super-seed used for seed generation.
+	Indices          []int                       // Selected run indices. Empty for all.
+	Observers        ObserversDef                // Observer definitions.
+	Systems          []string                    // Custom systems. Empty for the default systems.
+	Hooks            []HookDef                   // Lifecycle hooks. Empty for none.
+	FromSnapshot     string                      // Snapshot file runs were started from, relative to the working directory. Empty for none.
+	SaveSnapshot     string                      // Snapshot file saved by each run, relative to the output directory. Empty for none.
+	SnapshotTick     int                         // Tick snapshots were saved at.
+	Threads          int                         // Number of threads.
+	TPS              float64                     // Speed limit in ticks per second.
+	RenderDir        string                      // Directory for headless rendering. Empty for none.
+	EditLog          string                      // Log file of live parameter edits, relative to the output directory. Empty for none.
+	InputFiles       map[string]string           // SHA-256 hashes of input files, by path relative to the working directory.
+	Host             Host                        // Host information.
+	Started          time.Time                   // Start time of the experiment.
+	Finished         time.Time                   // End time of the experiment.
+	Duration         float64                     // Duration of the experiment in seconds.
+}
+
+// Versions of the executable and relevant modules.
+type Versions struct {
+	Main     string // Path and version of the main module.
+	BeecsCli string // Version of beecs-cli.
+	Beecs    string // Version of beecs.
+	Go       string // Go version used for building.
+}
+
+// Host information.
+type Host struct {
+	Hostname string
+	OS       string
+	Arch     string
+	CPUs     int
+}
+
+// ManifestFromFile reads a manifest from a JSON file.
+func ManifestFromFile(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer file.Close()
+
+	var m Manifest
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&m); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// GetVersions reads module versions from the build info.
+func GetVersions() Versions {
+	v := Versions{Go: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.Main = info.Main.Path + "@" + info.Main.Version
+	if info.Main.Path == cliModule {
+		v.BeecsCli = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		switch dep.Path {
+		case cliModule:
+			v.BeecsCli = dep.Version
+		case beecsModule:
+			v.Beecs = dep.Version
+		}
+	}
+	return v
+}
+
+// GetHost collects information on the host.
+func GetHost() Host {
+	name, _ := os.Hostname()
+	return Host{
+		Hostname: name,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CPUs:     runtime.NumCPU(),
+	}
+}
+
+// HashFile calculates the SHA-256 hash of a file.
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFiles calculates the SHA-256 hashes of files relative to a directory.
+func HashFiles(dir string, files []string) (map[string]string, error) {
+	hashes := map[string]string{}
+	for _, f := range files {
+		hash, err := HashFile(filepath.Join(dir, f))
+		if err != nil {
+			return nil, err
+		}
+		hashes[f] = hash
+	}
+	return hashes, nil
+}
+
+// ResolveParameters applies parameter overwrites to parameters in JSON format,
+// as produced by CustomParams.ToJSON.
+func ResolveParameters(js []byte, overwrite []experiment.ParameterValue) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	var tree map[string]any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	for _, par := range overwrite {
+		if err := setJSONParameter(tree, par); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(tree, "", "    ")
+}
+
+// ReferencedFiles collects all string values in parameters in JSON format
+// that are paths of existing files relative to the given directory.
+func ReferencedFiles(js []byte, dir string) ([]string, error) {
+	var tree any
+	if err := json.Unmarshal(js, &tree); err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	collectFiles(tree, dir, files)
+
+	result := make([]string, 0, len(files))
+	for f := range files {
+		result = append(result, f)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func collectFiles(value any, dir string, files map[string]bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, vv := range v {
+			collectFiles(vv, dir, files)
+		}
+	case []any:
+		for _, vv := range v {
+			collectFiles(vv, dir, files)
+		}
+	case string:
+		if v == "" {
+			return
+		}
+		if info, err := os.Stat(filepath.Join(dir, v)); err == nil && !info.IsDir() {
+			files[v] = true
+		}
+	}
+}
+
+// setJSONParameter sets a parameter like "params.Termination.MaxTicks"
+// in a parameter tree with keys "Parameters" and "Custom".
+func setJSONParameter(tree map[string]any, par experiment.ParameterValue) error {
+	parts := strings.Split(par.Parameter, ".")
+	if len(parts) < 3 {
+		return fmt.Errorf("invalid parameter name '%s'", par.Parameter)
+	}
+	typeName := parts[0] + "." + parts[1]
+
+	var group any
+	if custom, ok := tree["Custom"].(map[string]any); ok {
+		group, ok = custom[typeName]
+	}
+	if group == nil {
+		if defaults, ok := tree["Parameters"].(map[string]any); ok {
+			group = defaults[parts[1]]
+		}
+	}
+	if group == nil {
+		return fmt.Errorf("parameter group '%s' not found", typeName)
+	}
+
+	fields := parts[2:]
+	for i, f := range fields {
+		m, ok := group.(map[string]any)
+		if !ok {
+			return fmt.Errorf("parameter '%s' not found", par.Parameter)
+		}
+		if i == len(fields)-1 {
+			if _, ok := m[f]; !ok {
+				return fmt.Errorf("parameter '%s' not found", par.Parameter)
+			}
+			m[f] = jsonValue(m[f], par.Value)
+			break
+		}
+		group = m[f]
+	}
+	return nil
+}
+
+// jsonValue converts an overwrite value to the type of the original JSON value.
+func jsonValue(original any, value any) any {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch original.(type) {
+	case json.Number:
+		if _, err := strconv.ParseFloat(str, 64); err == nil {
+			return json.Number(str)
+		}
+	case bool:
+		if b, err := strconv.ParseBool(str); err == nil {
+			return b
+		}
+	case string:
+		return str
+	}
+	var v any
+	if err := json.Unmarshal([]byte(str), &v); err == nil {
+		return v
+	}
+	return str
+}
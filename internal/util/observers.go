@@ -4,27 +4,27 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"path"
 	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/mlange-42/ark-pixel/monitor"
 	"github.com/mlange-42/ark-pixel/plot"
 	"github.com/mlange-42/ark-pixel/window"
+	"github.com/mlange-42/ark-tools/app"
 	"github.com/mlange-42/ark-tools/observer"
 	"github.com/mlange-42/ark-tools/reporter"
+	"github.com/mlange-42/beecs-cli/events"
+	"github.com/mlange-42/beecs-cli/internal/render"
+	"github.com/mlange-42/beecs-cli/internal/serve"
 	"github.com/mlange-42/beecs-cli/registry"
+	"github.com/mlange-42/beecs-cli/view"
 )
 
-type entry struct {
-	Bytes []byte
-}
-
-func (e *entry) UnmarshalJSON(jsonData []byte) error {
-	e.Bytes = jsonData
-	return nil
-}
-
 type Observers struct {
 	Windows    []*window.Window
+	Drawers    []*render.WindowDrawer // Drawers of the windows that report errors.
 	Tables     []*reporter.RowCallback
 	StepTables []*reporter.TableCallback
 }
@@ -33,19 +33,22 @@ type TimeSeriesPlotDef struct {
 	Labels         plot.Labels
 	Title          string
 	Observer       string
-	ObserverConfig entry
+	ObserverConfig json.RawMessage
 	Columns        []string
 	Bounds         window.Bounds
 	DrawInterval   int
 	UpdateInterval int
 	MaxRows        int
+	Overlay        bool           // Draw completed runs of an experiment as faint lines behind the current run.
+	Transform      []TransformDef // Derived columns, added to the observer's columns. Optional.
+	Static         StaticPlotDef  // Static plot from table output, for sub-command plot. Optional.
 }
 
 type LinePlotDef struct {
 	Labels         plot.Labels
 	Title          string
 	Observer       string
-	ObserverConfig entry
+	ObserverConfig json.RawMessage
 	X              string
 	Y              []string
 	Bounds         window.Bounds
@@ -54,29 +57,56 @@ type LinePlotDef struct {
 	YLim           [2]float64
 }
 
+type HistogramPlotDef struct {
+	Labels         plot.Labels
+	Title          string
+	Observer       string
+	ObserverConfig json.RawMessage
+	Column         string // Column to show. Uses the last value of each run.
+	Bins           int    // Number of bins. Default: 10.
+	Bounds         window.Bounds
+	DrawInterval   int
+	UpdateInterval int
+}
+
 type TableDef struct {
 	File           string
 	Observer       string
-	ObserverConfig entry
+	ObserverConfig json.RawMessage
 	UpdateInterval int
 	Final          bool
+	Columns        []string       // Columns to write, in the given order. Default: all.
+	StartTick      int            // First tick to write.
+	EndTick        *int           // Last tick to write (inclusive). Optional, no limit if not set.
+	AtTicks        []int          // Write only at these ticks. Default: all ticks.
+	Transform      []TransformDef // Derived columns, added to the observer's columns. Optional.
 }
 
 type StepTableDef struct {
 	File           string
 	Observer       string
-	ObserverConfig entry
+	ObserverConfig json.RawMessage
 	UpdateInterval int
 	Final          bool
+	Triggers       []TriggerDef // Write only on ticks where a trigger fires. Replaces UpdateInterval and Final. Optional.
 }
 
 type ViewDef struct {
 	Drawer       string
-	DrawerConfig entry
+	DrawerConfig json.RawMessage
 	Title        string
 	Bounds       window.Bounds
 	DrawInterval int
 	MaxRows      int
+	Animation    AnimationDef // Animated GIF or APNG output. Optional.
+}
+
+type AnimationDef struct {
+	File     string // Output file, with extension .gif or .png (APNG). The run index is appended to the name.
+	Interval int    // Interval between frames, in ticks. Default: DrawInterval of the view.
+	Width    int    // Image width in pixels. Default: width of the view.
+	Height   int    // Image height in pixels. Default: height of the view.
+	Delay    int    // Display time per frame, in milliseconds. Default: 100.
 }
 
 type ObserversDef struct {
@@ -84,19 +114,46 @@ type ObserversDef struct {
 	CsvSeparator    string              // Column separator for all CSV output.
 	TimeSeriesPlots []TimeSeriesPlotDef // Live time series plots.
 	LinePlots       []LinePlotDef       // Live line plots.
+	HistogramPlots  []HistogramPlotDef  // Live histograms across the runs of an experiment, per parameter set.
 	Views           []ViewDef           // Live views.
 	Tables          []TableDef          // CSV output with one row per update.
 	StepTables      []StepTableDef      // CSV output with a full table per update.
+	ResponsePlots   []ResponsePlotDef   // Static parameter-response plots, for sub-command plot.
+	EventLog        EventLogDef         // Event log output. Optional.
+}
+
+// OutputFiles returns the paths of all CSV output files.
+// The first entry is the parameters file, which may be empty.
+func (obs *ObserversDef) OutputFiles() []string {
+	files := []string{obs.Parameters}
+	for _, t := range obs.Tables {
+		files = append(files, t.File)
+	}
+	for _, t := range obs.StepTables {
+		files = append(files, t.File)
+	}
+	return files
 }
 
-func (obs *ObserversDef) CreateObservers(withUI bool) (Observers, error) {
+// CreateObservers creates windows for all live plots and views, if withUI is true, and reporters for all tables.
+// The history is used by time series overlays and histograms, and may be nil.
+func (obs *ObserversDef) CreateObservers(withUI bool, history *render.History) (Observers, error) {
 	windows := []*window.Window{}
+	drawers := []*render.WindowDrawer{}
 	if withUI {
-		win, err := createTimeSeriesPlots(obs.TimeSeriesPlots)
+		win, dr, err := createTimeSeriesPlots(obs.TimeSeriesPlots, history)
+		if err != nil {
+			return Observers{}, err
+		}
+		windows = append(windows, win...)
+		drawers = append(drawers, dr...)
+
+		win, dr, err = createHistogramPlots(obs.HistogramPlots, history)
 		if err != nil {
 			return Observers{}, err
 		}
 		windows = append(windows, win...)
+		drawers = append(drawers, dr...)
 
 		win, err = createLinePlots(obs.LinePlots)
 		if err != nil {
@@ -123,72 +180,437 @@ func (obs *ObserversDef) CreateObservers(withUI bool) (Observers, error) {
 
 	return Observers{
 		Windows:    windows,
+		Drawers:    drawers,
 		Tables:     tables,
 		StepTables: stepTables,
 	}, nil
 }
 
-func createTimeSeriesPlots(plots []TimeSeriesPlotDef) ([]*window.Window, error) {
+// CreateEventDetector creates a system for detecting built-in events.
+// Returns nil if the event log is not enabled by a file or event types.
+func (obs *ObserversDef) CreateEventDetector() (*events.Detector, error) {
+	if obs.EventLog.File == "" && len(obs.EventLog.Events) == 0 {
+		return nil, nil
+	}
+	return events.NewDetector(obs.EventLog.Events, obs.EventLog.SwarmingThreshold)
+}
+
+// MultiRunPlots returns whether there are live plots that show data across runs,
+// i.e. histograms or time series with overlay.
+func (obs *ObserversDef) MultiRunPlots() bool {
+	if len(obs.HistogramPlots) > 0 {
+		return true
+	}
+	for _, p := range obs.TimeSeriesPlots {
+		if p.Overlay {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateRenderers creates systems for headless rendering of all plots and views
+// to numbered PNG files in sub-directories of the given directory.
+// Views that don't support drawing to images are skipped (see [ObserversDef.UnrenderableViews]).
+func (obs *ObserversDef) CreateRenderers(dir string) ([]app.System, error) {
+	systems := []app.System{}
+	n := 0
+	for _, p := range obs.TimeSeriesPlots {
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, err
+		}
+		obsCast, ok := observerVal.(observer.Row)
+		if !ok {
+			return nil, fmt.Errorf("type '%s' is not a Row observer", p.Observer)
+		}
+		obsCast, err = NewTransformed(obsCast, p.Transform)
+		if err != nil {
+			return nil, err
+		}
+		systems = append(systems, &render.Frames{
+			Drawer: &render.TimeSeries{
+				Observer:       obsCast,
+				Columns:        p.Columns,
+				UpdateInterval: p.UpdateInterval,
+				Labels:         p.Labels,
+				MaxRows:        p.MaxRows,
+			},
+			Dir:      path.Join(dir, frameDir(n, p.Title, p.Labels.Title)),
+			Interval: p.DrawInterval,
+			Width:    p.Bounds.W,
+			Height:   p.Bounds.H,
+		})
+		n++
+	}
+
+	for _, p := range obs.LinePlots {
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, err
+		}
+		obsCast, ok := observerVal.(observer.Table)
+		if !ok {
+			return nil, fmt.Errorf("type '%s' is not a Table observer", p.Observer)
+		}
+		systems = append(systems, &render.Frames{
+			Drawer: &render.Lines{
+				Observer: obsCast,
+				X:        p.X,
+				Y:        p.Y,
+				Labels:   p.Labels,
+				XLim:     p.XLim,
+				YLim:     p.YLim,
+			},
+			Dir:      path.Join(dir, frameDir(n, p.Title, p.Labels.Title)),
+			Interval: p.DrawInterval,
+			Width:    p.Bounds.W,
+			Height:   p.Bounds.H,
+		})
+		n++
+	}
+
+	for _, p := range obs.Views {
+		drawerVal, err := decodeDrawer(p.Drawer, p.DrawerConfig)
+		if err != nil {
+			return nil, err
+		}
+		drawerCast, ok := drawerVal.(view.ImageDrawer)
+		if ok {
+			systems = append(systems, &render.Frames{
+				Drawer:   &render.View{Drawer: drawerCast},
+				Dir:      path.Join(dir, frameDir(n, p.Title, p.Drawer)),
+				Interval: p.DrawInterval,
+				Width:    p.Bounds.W,
+				Height:   p.Bounds.H,
+			})
+		}
+		n++
+	}
+
+	return systems, nil
+}
+
+// CreateAnimations creates animation systems for all views with an animation file, for the run with the given index.
+// File paths are relative to the given output directory.
+func (obs *ObserversDef) CreateAnimations(dir string, index int) ([]app.System, error) {
+	systems := []app.System{}
+	for _, p := range obs.Views {
+		anim := p.Animation
+		if anim.File == "" {
+			continue
+		}
+		if err := render.CheckAnimationFile(anim.File); err != nil {
+			return nil, err
+		}
+		drawerVal, err := decodeDrawer(p.Drawer, p.DrawerConfig)
+		if err != nil {
+			return nil, err
+		}
+		drawerCast, ok := drawerVal.(view.ImageDrawer)
+		if !ok {
+			return nil, fmt.Errorf("view type '%s' does not support animation output", p.Drawer)
+		}
+
+		interval := anim.Interval
+		if interval <= 0 {
+			interval = p.DrawInterval
+		}
+		width, height := anim.Width, anim.Height
+		if width <= 0 {
+			width = p.Bounds.W
+		}
+		if height <= 0 {
+			height = p.Bounds.H
+		}
+		ext := path.Ext(anim.File)
+		file := fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(anim.File, ext), index, ext)
+
+		systems = append(systems, &render.Animation{
+			Drawer:   &render.View{Drawer: drawerCast},
+			File:     path.Join(dir, file),
+			Interval: interval,
+			Width:    width,
+			Height:   height,
+			Delay:    anim.Delay,
+		})
+	}
+	return systems, nil
+}
+
+// CreateDashboard creates systems that feed the plots and views to a live dashboard server, for the run with the given index.
+// Views that don't support image drawing are skipped.
+// Errors during the run, like in plot headers, are passed to fail.
+func (obs *ObserversDef) CreateDashboard(server *serve.Server, index int, fail func(err error)) ([]app.System, error) {
+	systems := []app.System{}
+	layout := serve.Layout{Run: index}
+
+	for i, p := range obs.TimeSeriesPlots {
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, err
+		}
+		obsCast, ok := observerVal.(observer.Row)
+		if !ok {
+			return nil, fmt.Errorf("type '%s' is not a Row observer", p.Observer)
+		}
+		obsCast, err = NewTransformed(obsCast, p.Transform)
+		if err != nil {
+			return nil, err
+		}
+		layout.TimeSeries = append(layout.TimeSeries, serve.PlotLayout{
+			Title:   plotTitle(p.Title, p.Labels.Title, p.Observer),
+			X:       p.Labels.X,
+			Y:       p.Labels.Y,
+			MaxRows: p.MaxRows,
+		})
+		filter, err := NewTableFilter(&TableDef{Columns: p.Columns})
+		if err != nil {
+			return nil, err
+		}
+		systems = append(systems, &reporter.RowCallback{
+			Observer:       obsCast,
+			UpdateInterval: p.UpdateInterval,
+			HeaderCallback: func(header []string) {
+				if err := ObserverError(obsCast); err != nil {
+					fail(fmt.Errorf("in dashboard plot '%s': %s", p.Observer, err.Error()))
+					return
+				}
+				header, err := filter.Header(header)
+				if err != nil {
+					fail(fmt.Errorf("in dashboard plot '%s': %s", p.Observer, err.Error()))
+					return
+				}
+				server.SetColumns(false, i, header)
+			},
+			Callback: func(step int, row []float64) {
+				server.AddRow(i, step, filter.Row(row))
+			},
+		})
+	}
+
+	for i, p := range obs.LinePlots {
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, err
+		}
+		obsCast, ok := observerVal.(observer.Table)
+		if !ok {
+			return nil, fmt.Errorf("type '%s' is not a Table observer", p.Observer)
+		}
+		layout.Lines = append(layout.Lines, serve.PlotLayout{
+			Title:    plotTitle(p.Title, p.Labels.Title, p.Observer),
+			X:        p.Labels.X,
+			Y:        p.Labels.Y,
+			XColumn:  p.X,
+			YColumns: p.Y,
+			XLim:     p.XLim,
+			YLim:     p.YLim,
+		})
+		systems = append(systems, &reporter.TableCallback{
+			Observer:       obsCast,
+			UpdateInterval: p.DrawInterval,
+			HeaderCallback: func(header []string) {
+				server.SetColumns(true, i, header)
+			},
+			Callback: func(step int, table [][]float64) {
+				server.UpdateTable(i, step, table)
+			},
+		})
+	}
+
+	for _, p := range obs.Views {
+		drawerVal, err := decodeDrawer(p.Drawer, p.DrawerConfig)
+		if err != nil {
+			return nil, err
+		}
+		drawerCast, ok := drawerVal.(view.ImageDrawer)
+		if !ok {
+			continue
+		}
+		systems = append(systems, &serve.View{
+			Server:   server,
+			Index:    len(layout.Views),
+			Drawer:   &render.View{Drawer: drawerCast},
+			Interval: p.DrawInterval,
+			Width:    p.Bounds.W,
+			Height:   p.Bounds.H,
+		})
+		layout.Views = append(layout.Views, serve.PlotLayout{Title: plotTitle(p.Title, p.Drawer)})
+	}
+
+	server.Reset(layout)
+	return systems, nil
+}
+
+// plotTitle returns the first non-empty name.
+func plotTitle(names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// UnrenderableViews returns the drawer names of all views that don't support headless rendering.
+func (obs *ObserversDef) UnrenderableViews() []string {
+	names := []string{}
+	for _, p := range obs.Views {
+		tp, ok := registry.GetDrawer(p.Drawer)
+		if !ok {
+			continue
+		}
+		if !reflect.PointerTo(tp).Implements(reflect.TypeFor[view.ImageDrawer]()) {
+			names = append(names, p.Drawer)
+		}
+	}
+	return names
+}
+
+var nonFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// frameDir creates a directory name for rendered frames, from an index and the first non-empty name.
+func frameDir(index int, names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return fmt.Sprintf("%02d-%s", index, nonFileChars.ReplaceAllString(name, "_"))
+		}
+	}
+	return fmt.Sprintf("%02d", index)
+}
+
+// decodeObserver creates a registered observer from its JSON configuration.
+func decodeObserver(name string, config json.RawMessage) (any, error) {
+	tp, ok := registry.GetObserver(name)
+	if !ok {
+		return nil, fmt.Errorf("observer type '%s' is not registered", name)
+	}
+	return decodeConfig(tp, config)
+}
+
+// decodeDrawer creates a registered drawer from its JSON configuration.
+func decodeDrawer(name string, config json.RawMessage) (any, error) {
+	tp, ok := registry.GetDrawer(name)
+	if !ok {
+		return nil, fmt.Errorf("view type '%s' is not registered", name)
+	}
+	return decodeConfig(tp, config)
+}
+
+// decodeConfig creates a new value of the given type, and decodes its JSON configuration into it.
+// An empty or null configuration results in the type's zero value.
+func decodeConfig(tp reflect.Type, config json.RawMessage) (any, error) {
+	val := reflect.New(tp).Interface()
+	if len(config) == 0 || string(bytes.TrimSpace(config)) == "null" {
+		config = []byte("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+func createTimeSeriesPlots(plots []TimeSeriesPlotDef, history *render.History) ([]*window.Window, []*render.WindowDrawer, error) {
 	windows := make([]*window.Window, len(plots))
+	drawers := []*render.WindowDrawer{}
 	for i, p := range plots {
-		tp, ok := registry.GetObserver(p.Observer)
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		obsCast, ok := observerVal.(observer.Row)
 		if !ok {
-			return nil, fmt.Errorf("observer type '%s' is not registered", p.Observer)
+			return nil, nil, fmt.Errorf("type '%s' is not a Row observer", p.Observer)
+		}
+		obsCast, err = NewTransformed(obsCast, p.Transform)
+		if err != nil {
+			return nil, nil, err
+		}
+		win := &window.Window{
+			Title:        p.Title,
+			Bounds:       p.Bounds,
+			DrawInterval: p.DrawInterval,
 		}
-		observerVal := reflect.New(tp).Interface()
-		if len(p.ObserverConfig.Bytes) == 0 {
-			p.ObserverConfig.Bytes = []byte("{}")
+		if p.Overlay {
+			drawer := &render.WindowDrawer{Drawer: &render.TimeSeries{
+				Observer:       obsCast,
+				Columns:        p.Columns,
+				UpdateInterval: p.UpdateInterval,
+				Labels:         p.Labels,
+				MaxRows:        p.MaxRows,
+				History:        history,
+				Plot:           i,
+			}}
+			win = win.With(drawer)
+			drawers = append(drawers, drawer)
+		} else {
+			win = win.With(&plot.TimeSeries{
+				Observer:       obsCast,
+				Columns:        p.Columns,
+				UpdateInterval: p.UpdateInterval,
+				Labels:         p.Labels,
+				MaxRows:        p.MaxRows,
+			})
 		}
+		win = win.With(&monitor.Controls{})
 
-		decoder := json.NewDecoder(bytes.NewReader(p.ObserverConfig.Bytes))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&observerVal); err != nil {
-			return nil, err
+		windows[i] = win
+	}
+
+	return windows, drawers, nil
+}
+
+func createHistogramPlots(plots []HistogramPlotDef, history *render.History) ([]*window.Window, []*render.WindowDrawer, error) {
+	windows := make([]*window.Window, len(plots))
+	drawers := make([]*render.WindowDrawer, len(plots))
+	for i, p := range plots {
+		if p.Column == "" {
+			return nil, nil, fmt.Errorf("histogram plot of observer '%s' requires a Column", p.Observer)
+		}
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
+			return nil, nil, err
 		}
 		obsCast, ok := observerVal.(observer.Row)
 		if !ok {
-			return nil, fmt.Errorf("type '%s' is not a Row observer", tp.String())
+			return nil, nil, fmt.Errorf("type '%s' is not a Row observer", p.Observer)
 		}
 		win := &window.Window{
 			Title:        p.Title,
 			Bounds:       p.Bounds,
 			DrawInterval: p.DrawInterval,
 		}
-		win = win.With(&plot.TimeSeries{
+		drawers[i] = &render.WindowDrawer{Drawer: &render.Histogram{
 			Observer:       obsCast,
-			Columns:        p.Columns,
+			Column:         p.Column,
+			Bins:           p.Bins,
 			UpdateInterval: p.UpdateInterval,
 			Labels:         p.Labels,
-			MaxRows:        p.MaxRows,
-		})
+			History:        history,
+			Plot:           i,
+		}}
+		win = win.With(drawers[i])
 		win = win.With(&monitor.Controls{})
 
 		windows[i] = win
 	}
 
-	return windows, nil
+	return windows, drawers, nil
 }
 
 func createLinePlots(plots []LinePlotDef) ([]*window.Window, error) {
 	windows := make([]*window.Window, len(plots))
 	for i, p := range plots {
-		tp, ok := registry.GetObserver(p.Observer)
-		if !ok {
-			return nil, fmt.Errorf("observer type '%s' is not registered", p.Observer)
-		}
-		observerVal := reflect.New(tp).Interface()
-		if len(p.ObserverConfig.Bytes) == 0 {
-			p.ObserverConfig.Bytes = []byte("{}")
-		}
-
-		decoder := json.NewDecoder(bytes.NewReader(p.ObserverConfig.Bytes))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&observerVal); err != nil {
+		observerVal, err := decodeObserver(p.Observer, p.ObserverConfig)
+		if err != nil {
 			return nil, err
 		}
 		obsCast, ok := observerVal.(observer.Table)
 		if !ok {
-			return nil, fmt.Errorf("type '%s' is not a Table observer", tp.String())
+			return nil, fmt.Errorf("type '%s' is not a Table observer", p.Observer)
 		}
 		win := &window.Window{
 			Title:        p.Title,
@@ -214,23 +636,13 @@ func createLinePlots(plots []LinePlotDef) ([]*window.Window, error) {
 func createViews(views []ViewDef) ([]*window.Window, error) {
 	windows := make([]*window.Window, len(views))
 	for i, p := range views {
-		tp, ok := registry.GetDrawer(p.Drawer)
-		if !ok {
-			return nil, fmt.Errorf("view type '%s' is not registered", p.Drawer)
-		}
-		drawerVal := reflect.New(tp).Interface()
-		if len(p.DrawerConfig.Bytes) == 0 {
-			p.DrawerConfig.Bytes = []byte("{}")
-		}
-
-		decoder := json.NewDecoder(bytes.NewReader(p.DrawerConfig.Bytes))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&drawerVal); err != nil {
+		drawerVal, err := decodeDrawer(p.Drawer, p.DrawerConfig)
+		if err != nil {
 			return nil, err
 		}
 		drawerCast, ok := drawerVal.(window.Drawer)
 		if !ok {
-			return nil, fmt.Errorf("type '%s' is not a Drawer", tp.String())
+			return nil, fmt.Errorf("type '%s' is not a Drawer", p.Drawer)
 		}
 		win := &window.Window{
 			Title:        p.Title,
@@ -248,22 +660,17 @@ func createViews(views []ViewDef) ([]*window.Window, error) {
 func createTables(tabs []TableDef) ([]*reporter.RowCallback, error) {
 	tables := []*reporter.RowCallback{}
 	for _, t := range tabs {
-		tp, ok := registry.GetObserver(t.Observer)
-		if !ok {
-			return nil, fmt.Errorf("observer type '%s' is not registered", t.Observer)
-		}
-		observerVal := reflect.New(tp).Interface()
-		if len(t.ObserverConfig.Bytes) == 0 {
-			t.ObserverConfig.Bytes = []byte("{}")
-		}
-		decoder := json.NewDecoder(bytes.NewReader(t.ObserverConfig.Bytes))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&observerVal); err != nil {
+		observerVal, err := decodeObserver(t.Observer, t.ObserverConfig)
+		if err != nil {
 			return nil, err
 		}
 		obsCast, ok := observerVal.(observer.Row)
 		if !ok {
-			return nil, fmt.Errorf("type '%s' is not a Row observer", tp.String())
+			return nil, fmt.Errorf("type '%s' is not a Row observer", t.Observer)
+		}
+		obsCast, err = NewTransformed(obsCast, t.Transform)
+		if err != nil {
+			return nil, err
 		}
 		rep := &reporter.RowCallback{
 			Observer:       obsCast,
@@ -281,26 +688,29 @@ func createTables(tabs []TableDef) ([]*reporter.RowCallback, error) {
 func createStepTables(tabs []StepTableDef) ([]*reporter.TableCallback, error) {
 	tables := []*reporter.TableCallback{}
 	for _, t := range tabs {
-		tp, ok := registry.GetObserver(t.Observer)
-		if !ok {
-			return nil, fmt.Errorf("observer type '%s' is not registered", t.Observer)
-		}
-		observerVal := reflect.New(tp).Interface()
-		if len(t.ObserverConfig.Bytes) == 0 {
-			t.ObserverConfig.Bytes = []byte("{}")
-		}
-		decoder := json.NewDecoder(bytes.NewReader(t.ObserverConfig.Bytes))
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&observerVal); err != nil {
+		observerVal, err := decodeObserver(t.Observer, t.ObserverConfig)
+		if err != nil {
 			return nil, err
 		}
 		obsCast, ok := observerVal.(observer.Table)
 		if !ok {
-			return nil, fmt.Errorf("type '%s' is not a Table observer", tp.String())
+			return nil, fmt.Errorf("type '%s' is not a Table observer", t.Observer)
+		}
+		updateInterval := t.UpdateInterval
+		if len(t.Triggers) > 0 {
+			if t.Final {
+				return nil, fmt.Errorf("step table '%s' can't use Final together with Triggers", t.File)
+			}
+			// Triggers are checked on every tick.
+			updateInterval = 1
+		}
+		obsCast, err = NewTriggered(obsCast, t.Triggers)
+		if err != nil {
+			return nil, err
 		}
 		rep := &reporter.TableCallback{
 			Observer:       obsCast,
-			UpdateInterval: t.UpdateInterval,
+			UpdateInterval: updateInterval,
 			HeaderCallback: func(header []string) {},
 			Callback:       func(step int, row [][]float64) {},
 			Final:          t.Final,
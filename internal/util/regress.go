@@ -0,0 +1,291 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tolerance for comparing values.
+// Values a and b are considered equal if |a-b| <= Abs + Rel*|b|.
+type Tolerance struct {
+	Abs float64 // Absolute tolerance.
+	Rel float64 // Relative tolerance, relative to the baseline value.
+}
+
+// Equal checks whether a value is equal to a baseline value.
+func (t *Tolerance) Equal(value, baseline float64) bool {
+	if value == baseline || (math.IsNaN(value) && math.IsNaN(baseline)) {
+		return true
+	}
+	return math.Abs(value-baseline) <= t.Abs+t.Rel*math.Abs(baseline)
+}
+
+// RegressionDef defines tolerances and tests for regression testing.
+type RegressionDef struct {
+	Tolerance Tolerance            // Default tolerance for all columns.
+	Tables    []RegressionTableDef // Table-specific settings.
+}
+
+// RegressionTableDef defines tolerances and tests for a single table.
+type RegressionTableDef struct {
+	File        string               // Table file, as in the observers file.
+	NoPointwise bool                 // Skip value-by-value comparison, e.g. for intended stochastic changes.
+	Columns     map[string]Tolerance // Column-specific tolerances.
+	Tests       []KSTestDef          // Statistical tests across replicates.
+}
+
+// KSTestDef defines a two-sample Kolmogorov-Smirnov test of a column across replicates.
+type KSTestDef struct {
+	Column string  // Column to test.
+	Tick   *int    // Tick to test at. Default: final tick of each run.
+	Alpha  float64 // Significance level. Default: 0.05.
+}
+
+// KSResult is the result of a Kolmogorov-Smirnov test for a single parameter set.
+type KSResult struct {
+	Column string  // Tested column.
+	Set    string  // Parameter set.
+	N, M   int     // Sample sizes of current and baseline data.
+	D      float64 // KS statistic.
+	P      float64 // p-value.
+	Pass   bool    // Whether the test passed.
+}
+
+// Table returns the settings for the table with the given file, or default settings.
+func (r *RegressionDef) Table(file string) RegressionTableDef {
+	for _, t := range r.Tables {
+		if t.File == file {
+			return t
+		}
+	}
+	return RegressionTableDef{File: file}
+}
+
+// ParameterSets assigns runs to parameter sets, based on the parameter table.
+// Returns a map from run label to a parameter set label.
+func ParameterSets(params *CsvTable) map[string]string {
+	sets := map[string]string{}
+	first := params.Column("Finished") + 1
+	for _, row := range params.Rows {
+		parts := make([]string, 0, len(row)-first)
+		for i := first; i < len(row); i++ {
+			parts = append(parts, params.Header[i]+"="+row[i])
+		}
+		sets[row[0]] = strings.Join(parts, ",")
+	}
+	return sets
+}
+
+// CompareTolerance compares a table to a baseline table, run by run, using tolerances.
+// Returns the number of differing values and the first difference.
+// Columns in ignore are not compared.
+func CompareTolerance(table, baseline *CsvTable, def *RegressionTableDef, tol Tolerance, ignore []string) (int, *Difference, error) {
+	if !slices.Equal(table.Header, baseline.Header) {
+		return 1, &Difference{Message: fmt.Sprintf("headers differ: %v != %v", table.Header, baseline.Header)}, nil
+	}
+	tolerances := make([]Tolerance, len(table.Header))
+	for i, col := range table.Header {
+		tolerances[i] = tol
+		if t, ok := def.Columns[col]; ok {
+			tolerances[i] = t
+		}
+	}
+	tickCol := table.Column("Ticks")
+
+	runs, rows := table.RunRows()
+	baseRuns, baseRows := baseline.RunRows()
+	slices.Sort(runs)
+
+	count := 0
+	var first *Difference
+	for _, run := range baseRuns {
+		if _, ok := rows[run]; !ok {
+			count++
+			if first == nil {
+				first = &Difference{Run: run, Message: "run missing"}
+			}
+		}
+	}
+
+	for _, run := range runs {
+		ra := rows[run]
+		rb, ok := baseRows[run]
+		if !ok {
+			count++
+			if first == nil {
+				first = &Difference{Run: run, Message: "run missing in baseline"}
+			}
+			continue
+		}
+		if len(ra) != len(rb) {
+			count++
+			if first == nil {
+				first = &Difference{Run: run, Message: fmt.Sprintf("number of rows differs: %d != %d", len(ra), len(rb))}
+			}
+		}
+		for i := range min(len(ra), len(rb)) {
+			for j, col := range table.Header {
+				if ra[i][j] == rb[i][j] || slices.Contains(ignore, col) {
+					continue
+				}
+				a, err := strconv.ParseFloat(ra[i][j], 64)
+				if err != nil {
+					return 0, nil, err
+				}
+				b, err := strconv.ParseFloat(rb[i][j], 64)
+				if err != nil {
+					return 0, nil, err
+				}
+				if tolerances[j].Equal(a, b) {
+					continue
+				}
+				count++
+				if first == nil {
+					first = &Difference{Run: run, Column: col, A: ra[i][j], B: rb[i][j]}
+					if tickCol >= 0 {
+						first.Tick = ra[i][tickCol]
+					}
+				}
+			}
+		}
+	}
+	return count, first, nil
+}
+
+// TestReplicates performs a two-sample Kolmogorov-Smirnov test per parameter set,
+// comparing a column of a table to the same column of a baseline table.
+func TestReplicates(table, baseline *CsvTable, sets, baseSets map[string]string, test *KSTestDef) ([]KSResult, error) {
+	alpha := test.Alpha
+	if alpha == 0 {
+		alpha = 0.05
+	}
+
+	samples, err := sampleReplicates(table, sets, test)
+	if err != nil {
+		return nil, err
+	}
+	baseSamples, err := sampleReplicates(baseline, baseSets, test)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(samples))
+	for k := range samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	results := []KSResult{}
+	for _, set := range keys {
+		x := samples[set]
+		y, ok := baseSamples[set]
+		if !ok {
+			return nil, fmt.Errorf("parameter set '%s' not found in baseline", set)
+		}
+		d, p := KSTest(x, y)
+		results = append(results, KSResult{
+			Column: test.Column,
+			Set:    set,
+			N:      len(x),
+			M:      len(y),
+			D:      d,
+			P:      p,
+			Pass:   p >= alpha,
+		})
+	}
+	return results, nil
+}
+
+// sampleReplicates collects values of a column per parameter set,
+// at a given tick or the final tick of each run.
+func sampleReplicates(table *CsvTable, sets map[string]string, test *KSTestDef) (map[string][]float64, error) {
+	col := table.Column(test.Column)
+	if col < 0 {
+		return nil, fmt.Errorf("column '%s' not found", test.Column)
+	}
+	tickCol := table.Column("Ticks")
+	if test.Tick != nil && tickCol < 0 {
+		return nil, fmt.Errorf("table has no column 'Ticks'")
+	}
+
+	samples := map[string][]float64{}
+	runs, rows := table.RunRows()
+	for _, run := range runs {
+		runRows := rows[run]
+		var row []string
+		if test.Tick == nil {
+			row = runRows[len(runRows)-1]
+		} else {
+			tick := strconv.Itoa(*test.Tick)
+			for _, r := range runRows {
+				if r[tickCol] == tick {
+					row = r
+					break
+				}
+			}
+			if row == nil {
+				continue
+			}
+		}
+		v, err := strconv.ParseFloat(row[col], 64)
+		if err != nil {
+			return nil, err
+		}
+		set := sets[run]
+		samples[set] = append(samples[set], v)
+	}
+	return samples, nil
+}
+
+// KSTest performs a two-sample Kolmogorov-Smirnov test.
+// Returns the KS statistic D and the asymptotic p-value.
+func KSTest(x, y []float64) (float64, float64) {
+	if len(x) == 0 || len(y) == 0 {
+		return 0, 1
+	}
+	x = slices.Clone(x)
+	y = slices.Clone(y)
+	slices.Sort(x)
+	slices.Sort(y)
+
+	n, m := float64(len(x)), float64(len(y))
+	d := 0.0
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		v := min(x[i], y[j])
+		for i < len(x) && x[i] == v {
+			i++
+		}
+		for j < len(y) && y[j] == v {
+			j++
+		}
+		d = max(d, math.Abs(float64(i)/n-float64(j)/m))
+	}
+
+	ne := math.Sqrt(n * m / (n + m))
+	return d, ksProbability((ne + 0.12 + 0.11/ne) * d)
+}
+
+// ksProbability calculates the complementary Kolmogorov distribution function.
+func ksProbability(lambda float64) float64 {
+	if lambda < 1e-6 {
+		return 1
+	}
+	sum := 0.0
+	sign := 1.0
+	prev := 0.0
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		sum += term
+		if math.Abs(term) <= 1e-10*math.Abs(sum) || math.Abs(term) <= 1e-8*prev {
+			return min(max(sum, 0), 1)
+		}
+		sign = -sign
+		prev = math.Abs(term)
+	}
+	return 1
+}
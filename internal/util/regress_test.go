@@ -0,0 +1,74 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKSTest(t *testing.T) {
+	seq := func(from, to int) []float64 {
+		s := []float64{}
+		for i := from; i <= to; i++ {
+			s = append(s, float64(i))
+		}
+		return s
+	}
+	tests := []struct {
+		name       string
+		x, y       []float64
+		d          float64
+		pMin, pMax float64
+	}{
+		{"identical", seq(1, 10), seq(1, 10), 0, 1, 1},
+		{"unsorted", []float64{3, 1, 2}, []float64{2, 3, 1}, 0, 1, 1},
+		{"empty", nil, seq(1, 10), 0, 1, 1},
+		{"disjoint", seq(1, 10), seq(11, 20), 1, 0, 1e-4},
+		{"shifted", seq(1, 10), seq(6, 15), 0.5, 0.10, 0.12},
+		{"ties", []float64{1, 1, 2, 2}, []float64{1, 2, 2, 2}, 0.25, 0.99, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, p := KSTest(tt.x, tt.y)
+			if math.Abs(d-tt.d) > 1e-12 {
+				t.Errorf("expected D=%f, got %f", tt.d, d)
+			}
+			if p < tt.pMin || p > tt.pMax {
+				t.Errorf("expected p in [%f, %f], got %f", tt.pMin, tt.pMax, p)
+			}
+		})
+	}
+}
+
+func TestKSTestSymmetric(t *testing.T) {
+	x := []float64{0.1, 0.5, 0.7, 1.2, 3.0}
+	y := []float64{0.2, 0.3, 2.5, 2.7, 4.0, 5.0}
+	d1, p1 := KSTest(x, y)
+	d2, p2 := KSTest(y, x)
+	if d1 != d2 || p1 != p2 {
+		t.Errorf("expected symmetric results, got D=%f, p=%f and D=%f, p=%f", d1, p1, d2, p2)
+	}
+}
+
+func TestToleranceEqual(t *testing.T) {
+	tests := []struct {
+		name            string
+		tol             Tolerance
+		value, baseline float64
+		equal           bool
+	}{
+		{"exact", Tolerance{}, 1, 1, true},
+		{"exact differs", Tolerance{}, 1, 1.0000001, false},
+		{"nan", Tolerance{}, math.NaN(), math.NaN(), true},
+		{"abs", Tolerance{Abs: 0.1}, 1.05, 1, true},
+		{"abs exceeded", Tolerance{Abs: 0.1}, 1.2, 1, false},
+		{"rel", Tolerance{Rel: 0.01}, 101, 100, true},
+		{"rel exceeded", Tolerance{Rel: 0.01}, 102, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if eq := tt.tol.Equal(tt.value, tt.baseline); eq != tt.equal {
+				t.Errorf("expected %t, got %t", tt.equal, eq)
+			}
+		})
+	}
+}
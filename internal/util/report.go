@@ -0,0 +1,352 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlange-42/ark-pixel/plot"
+)
+
+// maxReportSets is the maximum number of parameter sets shown separately in report plots.
+// With more sets, envelopes are calculated over all runs.
+const maxReportSets = 5
+
+const (
+	reportPlotWidth  = 20.0
+	reportPlotHeight = 10.0
+)
+
+// Report is a self-contained HTML report of an experiment.
+type Report struct {
+	Manifest   *Manifest
+	Created    time.Time
+	Design     ReportTable
+	RunTimes   ReportTable
+	Plots      []ReportPlot
+	Extinction []ReportTable
+	Parameters string
+	Files      []ReportFile
+}
+
+// ReportTable is a table in a report.
+type ReportTable struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// ReportPlot is an embedded plot in a report.
+type ReportPlot struct {
+	Title string
+	Image template.URL
+}
+
+// ReportFile is an embedded input file in a report.
+// Files that changed since the experiment was run are not embedded.
+type ReportFile struct {
+	Path    string
+	Hash    string // SHA-256 hash recorded in the manifest.
+	Changed bool   // Whether the file's content does not match the recorded hash.
+	Content string
+}
+
+// NewReport creates a report from an experiment's manifest and the output in the given directory.
+func NewReport(m *Manifest, dir string) (Report, error) {
+	report := Report{
+		Manifest:   m,
+		Created:    time.Now(),
+		Parameters: string(m.Parameters),
+	}
+	obs := &m.Observers
+
+	var sets map[string]string
+	if obs.Parameters != "" {
+		params, err := ReadCsv(path.Join(dir, obs.Parameters), obs.CsvSeparator)
+		if err != nil {
+			return report, err
+		}
+		sets = ParameterSets(&params)
+		report.Design = designTable(&params)
+		report.RunTimes, err = runTimesTable(&params)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	plotSets := sets
+	if countSets(sets) > maxReportSets {
+		plotSets = nil
+	}
+	for _, t := range obs.Tables {
+		table, err := ReadCsv(path.Join(dir, t.File), obs.CsvSeparator)
+		if err != nil {
+			return report, err
+		}
+		if t.Observer == "obs.Extinction" {
+			ext, err := summaryTable(&table, sets)
+			if err != nil {
+				return report, err
+			}
+			ext.Title = t.File
+			report.Extinction = append(report.Extinction, ext)
+		}
+		if t.Final {
+			continue
+		}
+		def := TimeSeriesPlotDef{
+			Labels: plot.Labels{Title: t.File, X: "Ticks"},
+			Static: StaticPlotDef{Style: StyleSD},
+		}
+		sp, err := TimeSeriesStatic(&table, &def, plotSets)
+		if err != nil {
+			return report, fmt.Errorf("in report plot for '%s': %s", t.File, err.Error())
+		}
+		img, err := sp.Encode("svg", reportPlotWidth, reportPlotHeight)
+		if err != nil {
+			return report, err
+		}
+		report.Plots = append(report.Plots, ReportPlot{
+			Title: t.File,
+			Image: template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(img)),
+		})
+	}
+
+	files := make([]string, 0, len(m.InputFiles))
+	for f := range m.InputFiles {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		report.Files = append(report.Files, reportFile(m.WorkingDirectory, f, m.InputFiles[f]))
+	}
+
+	return report, nil
+}
+
+// reportFile reads an input file for embedding in a report.
+// The content is only embedded if it matches the hash recorded in the manifest.
+func reportFile(dir string, file string, hash string) ReportFile {
+	content, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return ReportFile{Path: file, Hash: hash, Content: fmt.Sprintf("(file not available: %s)", err.Error())}
+	}
+	sum := sha256.Sum256(content)
+	if actual := hex.EncodeToString(sum[:]); actual != hash {
+		return ReportFile{Path: file, Hash: hash, Changed: true,
+			Content: fmt.Sprintf("(file changed since the experiment was run, SHA-256 %s; not embedded)", actual)}
+	}
+	return ReportFile{Path: file, Hash: hash, Content: string(content)}
+}
+
+// Write the report as HTML.
+func (r *Report) Write(w io.Writer) error {
+	tpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, r)
+}
+
+// designTable lists the distinct parameter sets and the number of runs per set.
+func designTable(params *CsvTable) ReportTable {
+	first := params.Column("Finished") + 1
+	table := ReportTable{
+		Header: append(append([]string{"Set"}, params.Header[first:]...), "Runs", "First run"),
+	}
+	index := map[string]int{}
+	for _, row := range params.Rows {
+		key := strings.Join(row[first:], ",")
+		idx, ok := index[key]
+		if !ok {
+			idx = len(table.Rows)
+			index[key] = idx
+			r := append([]string{strconv.Itoa(idx)}, row[first:]...)
+			table.Rows = append(table.Rows, append(r, "0", row[0]))
+		}
+		runs := table.Rows[idx]
+		n, _ := strconv.Atoi(runs[len(runs)-2])
+		runs[len(runs)-2] = strconv.Itoa(n + 1)
+	}
+	return table
+}
+
+// runTimesTable summarizes run times from columns "Started" and "Finished".
+func runTimesTable(params *CsvTable) (ReportTable, error) {
+	table := ReportTable{
+		Header: []string{"Runs", "Total [s]", "Mean [s]", "SD [s]", "Min [s]", "Max [s]"},
+	}
+	startCol, endCol := params.Column("Started"), params.Column("Finished")
+	if startCol < 0 || endCol < 0 || len(params.Rows) == 0 {
+		return table, nil
+	}
+	times := make([]float64, len(params.Rows))
+	minStart, maxEnd := math.Inf(1), math.Inf(-1)
+	for i, row := range params.Rows {
+		start, err := strconv.ParseFloat(row[startCol], 64)
+		if err != nil {
+			return table, err
+		}
+		end, err := strconv.ParseFloat(row[endCol], 64)
+		if err != nil {
+			return table, err
+		}
+		times[i] = (end - start) / 1000
+		minStart = math.Min(minStart, start)
+		maxEnd = math.Max(maxEnd, end)
+	}
+	mean, sd := meanSD(times)
+	sort.Float64s(times)
+	table.Rows = [][]string{{
+		strconv.Itoa(len(times)),
+		formatValue((maxEnd - minStart) / 1000),
+		formatValue(mean), formatValue(sd),
+		formatValue(times[0]), formatValue(times[len(times)-1]),
+	}}
+	return table, nil
+}
+
+// summaryTable calculates mean, SD, minimum and maximum of all data columns per parameter set.
+func summaryTable(table *CsvTable, sets map[string]string) (ReportTable, error) {
+	columns, indices, err := dataColumns(table, nil)
+	if err != nil {
+		return ReportTable{}, err
+	}
+	result := ReportTable{Header: []string{"Parameters", "Runs"}}
+	for _, c := range columns {
+		result.Header = append(result.Header, c+" mean", c+" SD", c+" min", c+" max")
+	}
+
+	runs, rows := table.RunRows()
+	groups, groupRuns := groupRuns(runs, sets)
+	for _, group := range groups {
+		row := []string{group, strconv.Itoa(len(groupRuns[group]))}
+		for _, col := range indices {
+			values := []float64{}
+			for _, run := range groupRuns[group] {
+				runRows := rows[run]
+				v, err := strconv.ParseFloat(runRows[len(runRows)-1][col], 64)
+				if err != nil {
+					return result, err
+				}
+				if !math.IsNaN(v) {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 {
+				row = append(row, "", "", "", "")
+				continue
+			}
+			mean, sd := meanSD(values)
+			sort.Float64s(values)
+			row = append(row, formatValue(mean), formatValue(sd), formatValue(values[0]), formatValue(values[len(values)-1]))
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+func countSets(sets map[string]string) int {
+	distinct := map[string]bool{}
+	for _, s := range sets {
+		distinct[s] = true
+	}
+	return len(distinct)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 5, 64)
+}
+
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>beecs experiment report</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1100px; color: #222; }
+h1, h2 { border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; margin: 1em 0; font-size: 90%; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: right; }
+th { background: #eee; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; max-height: 30em; }
+img { max-width: 100%; }
+.meta td { text-align: left; }
+</style>
+</head>
+<body>
+<h1>beecs experiment report</h1>
+{{with .Manifest}}
+<table class="meta">
+<tr><th>Command</th><td><code>{{range .CommandLine}}{{.}} {{end}}</code></td></tr>
+<tr><th>Working directory</th><td>{{.WorkingDirectory}}</td></tr>
+<tr><th>Output directory</th><td>{{.OutputDirectory}}</td></tr>
+<tr><th>Versions</th><td>beecs-cli {{.Versions.BeecsCli}}, beecs {{.Versions.Beecs}}, {{.Versions.Go}}</td></tr>
+<tr><th>Host</th><td>{{.Host.Hostname}} ({{.Host.OS}}/{{.Host.Arch}}, {{.Host.CPUs}} CPUs)</td></tr>
+<tr><th>Runs per parameter set</th><td>{{.Runs}}</td></tr>
+<tr><th>Super-seed</th><td>{{.SuperSeed}}</td></tr>
+<tr><th>Threads</th><td>{{.Threads}}</td></tr>
+<tr><th>Started</th><td>{{.Started.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>Duration</th><td>{{printf "%.1f" .Duration}} s</td></tr>
+</table>
+{{end}}
+<p>Report created {{.Created.Format "2006-01-02 15:04:05"}}</p>
+
+<h2>Parameter design</h2>
+{{template "table" .Design}}
+
+<h2>Run times</h2>
+{{template "table" .RunTimes}}
+
+<h2>Summary plots</h2>
+<p>Mean &plusmn; SD over replicates.</p>
+{{range .Plots}}
+<h3>{{.Title}}</h3>
+<img src="{{.Image}}" alt="{{.Title}}">
+{{else}}
+<p>No time series tables configured.</p>
+{{end}}
+
+<h2>Extinction</h2>
+{{range .Extinction}}
+<h3>{{.Title}}</h3>
+{{template "table" .}}
+{{else}}
+<p>No table with observer <code>obs.Extinction</code> configured.</p>
+{{end}}
+
+<h2>Input files</h2>
+<details>
+<summary>Resolved parameters</summary>
+<pre>{{.Parameters}}</pre>
+</details>
+{{range .Files}}
+<details>
+<summary>{{.Path}} <small>(SHA-256 {{.Hash}})</small>{{if .Changed}} <strong>changed since the run</strong>{{end}}</summary>
+<pre>{{.Content}}</pre>
+</details>
+{{end}}
+</body>
+</html>
+{{define "table"}}
+{{if .Header}}
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{else}}
+<p>No data available.</p>
+{{end}}
+{{end}}
+`
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReportFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "params.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := HashFile(filepath.Join(dir, "params.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		hash    string
+		changed bool
+		content string
+	}{
+		{"unchanged", "params.json", hash, false, "{}"},
+		{"changed", "params.json", "0000", true, ""},
+		{"missing", "missing.json", hash, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := reportFile(dir, tt.file, tt.hash)
+			if f.Changed != tt.changed {
+				t.Errorf("expected changed %t, got %t", tt.changed, f.Changed)
+			}
+			if tt.content != "" && f.Content != tt.content {
+				t.Errorf("expected content %q, got %q", tt.content, f.Content)
+			}
+			if tt.content == "" && f.Content == "{}" {
+				t.Errorf("content must not be embedded")
+			}
+		})
+	}
+}
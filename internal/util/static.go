@@ -0,0 +1,398 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mlange-42/ark-pixel/plot"
+	"github.com/mlange-42/beecs-cli/internal/render"
+)
+
+// Styles of static time series plots.
+const (
+	StyleLines     = "lines"
+	StyleSD        = "sd"
+	StyleQuantiles = "quantiles"
+)
+
+const (
+	defaultPlotWidth  = 16.0
+	defaultPlotHeight = 10.0
+)
+
+type StaticPlotDef struct {
+	File      string     // Output file, with extension .png, .svg or .pdf. Empty for no static plot.
+	Table     string     // Input CSV file. Default: file of the first table with the same observer.
+	Style     string     // "lines" for each run (default), "sd" for mean ± SD, or "quantiles" for median and quantile band.
+	Quantiles [2]float64 // Quantiles for style "quantiles". Default: [0.05, 0.95].
+	Width     float64    // Width in centimeters. Default: 16.
+	Height    float64    // Height in centimeters. Default: 10.
+}
+
+type ResponsePlotDef struct {
+	Labels    plot.Labels
+	File      string   // Output file, with extension .png, .svg or .pdf.
+	Table     string   // Input CSV file, written by a table.
+	Parameter string   // Parameter for the x axis, as in the experiment file.
+	Columns   []string // Response columns. Default: all.
+	Tick      *int     // Tick of the response values. Default: last tick of each run.
+	Width     float64  // Width in centimeters. Default: 16.
+	Height    float64  // Height in centimeters. Default: 10.
+}
+
+// StaticTable returns the input CSV file of a static time series plot.
+func (obs *ObserversDef) StaticTable(p *TimeSeriesPlotDef) (string, error) {
+	if p.Static.Table != "" {
+		return p.Static.Table, nil
+	}
+	for _, t := range obs.Tables {
+		if t.Observer == p.Observer {
+			return t.File, nil
+		}
+	}
+	return "", fmt.Errorf("no table for observer '%s' found for static plot '%s'", p.Observer, p.Static.File)
+}
+
+// SaveStaticPlots creates all static plots, from table output in the given directory.
+// Returns the paths of the created files.
+func (obs *ObserversDef) SaveStaticPlots(dir string) ([]string, error) {
+	files := []string{}
+
+	var sets map[string]string
+	if obs.Parameters != "" {
+		params, err := ReadCsv(path.Join(dir, obs.Parameters), obs.CsvSeparator)
+		if err != nil {
+			return nil, err
+		}
+		sets = ParameterSets(&params)
+	}
+
+	for i := range obs.TimeSeriesPlots {
+		p := &obs.TimeSeriesPlots[i]
+		if p.Static.File == "" {
+			continue
+		}
+		file, err := obs.StaticTable(p)
+		if err != nil {
+			return nil, err
+		}
+		table, err := ReadCsv(path.Join(dir, file), obs.CsvSeparator)
+		if err != nil {
+			return nil, err
+		}
+		sp, err := TimeSeriesStatic(&table, p, sets)
+		if err != nil {
+			return nil, fmt.Errorf("in static plot '%s': %s", p.Static.File, err.Error())
+		}
+		out := path.Join(dir, p.Static.File)
+		if err := savePlot(&sp, out, p.Static.Width, p.Static.Height); err != nil {
+			return nil, err
+		}
+		files = append(files, out)
+	}
+
+	if len(obs.ResponsePlots) == 0 {
+		return files, nil
+	}
+	if obs.Parameters == "" {
+		return nil, fmt.Errorf("response plots require a parameters file")
+	}
+	params, err := ReadCsv(path.Join(dir, obs.Parameters), obs.CsvSeparator)
+	if err != nil {
+		return nil, err
+	}
+	for i := range obs.ResponsePlots {
+		p := &obs.ResponsePlots[i]
+		table, err := ReadCsv(path.Join(dir, p.Table), obs.CsvSeparator)
+		if err != nil {
+			return nil, err
+		}
+		sp, err := ResponseStatic(&table, &params, p)
+		if err != nil {
+			return nil, fmt.Errorf("in response plot '%s': %s", p.File, err.Error())
+		}
+		out := path.Join(dir, p.File)
+		if err := savePlot(&sp, out, p.Width, p.Height); err != nil {
+			return nil, err
+		}
+		files = append(files, out)
+	}
+
+	return files, nil
+}
+
+// TimeSeriesStatic creates a static time series plot from a table with columns "Run" and "Ticks".
+// Sets map run labels to parameter sets, for grouping replicates. It may be nil.
+func TimeSeriesStatic(table *CsvTable, def *TimeSeriesPlotDef, sets map[string]string) (render.StaticPlot, error) {
+	plot := render.StaticPlot{Labels: def.Labels}
+
+	tickCol := table.Column("Ticks")
+	if tickCol < 0 {
+		return plot, fmt.Errorf("missing column 'Ticks'")
+	}
+	columns, indices, err := dataColumns(table, def.Columns)
+	if err != nil {
+		return plot, err
+	}
+
+	runs, rows := table.RunRows()
+	groups, groupRuns := groupRuns(runs, sets)
+
+	style := def.Static.Style
+	if style == "" {
+		style = StyleLines
+	}
+	quantiles := def.Static.Quantiles
+	if quantiles == [2]float64{} {
+		quantiles = [2]float64{0.05, 0.95}
+	}
+
+	for c, col := range indices {
+		for g, group := range groups {
+			label := columns[c]
+			if len(groups) > 1 {
+				label = fmt.Sprintf("%s [%s]", columns[c], group)
+			}
+			color := c*len(groups) + g
+
+			if style == StyleLines {
+				for r, run := range groupRuns[group] {
+					x, y, err := parseColumns(rows[run], tickCol, col)
+					if err != nil {
+						return plot, err
+					}
+					s := render.Series{Color: color, X: x, Y: y}
+					if r == 0 {
+						s.Label = label
+					}
+					plot.Lines = append(plot.Lines, s)
+				}
+				continue
+			}
+
+			ticks, values, err := collectTicks(rows, groupRuns[group], tickCol, col)
+			if err != nil {
+				return plot, err
+			}
+			band := render.Band{Label: label, Color: color}
+			for t, tick := range ticks {
+				v := values[t]
+				var low, mid, high float64
+				switch style {
+				case StyleSD:
+					mid, high = meanSD(v)
+					low, high = mid-high, mid+high
+				case StyleQuantiles:
+					slices.Sort(v)
+					low, mid, high = quantile(v, quantiles[0]), quantile(v, 0.5), quantile(v, quantiles[1])
+				default:
+					return plot, fmt.Errorf("unknown plot style '%s'", style)
+				}
+				band.X = append(band.X, tick)
+				band.Low = append(band.Low, low)
+				band.Mid = append(band.Mid, mid)
+				band.High = append(band.High, high)
+			}
+			plot.Bands = append(plot.Bands, band)
+		}
+	}
+	return plot, nil
+}
+
+// ResponseStatic creates a static scatter plot of a response over a parameter,
+// with one point per run.
+func ResponseStatic(table, params *CsvTable, def *ResponsePlotDef) (render.StaticPlot, error) {
+	plot := render.StaticPlot{Labels: def.Labels}
+	if plot.Labels.X == "" {
+		plot.Labels.X = def.Parameter
+	}
+
+	parCol := params.Column(def.Parameter)
+	if parCol < 0 {
+		return plot, fmt.Errorf("parameter '%s' not found in parameters file", def.Parameter)
+	}
+	parValues := map[string]float64{}
+	for _, row := range params.Rows {
+		v, err := strconv.ParseFloat(row[parCol], 64)
+		if err != nil {
+			return plot, err
+		}
+		parValues[row[0]] = v
+	}
+
+	tickCol := table.Column("Ticks")
+	columns, indices, err := dataColumns(table, def.Columns)
+	if err != nil {
+		return plot, err
+	}
+
+	runs, rows := table.RunRows()
+	for c, col := range indices {
+		s := render.Series{Label: columns[c], Color: c}
+		for _, run := range runs {
+			x, ok := parValues[run]
+			if !ok {
+				return plot, fmt.Errorf("run %s not found in parameters file", run)
+			}
+			row, err := responseRow(rows[run], tickCol, def.Tick)
+			if err != nil {
+				return plot, err
+			}
+			if row == nil {
+				continue
+			}
+			y, err := strconv.ParseFloat(row[col], 64)
+			if err != nil {
+				return plot, err
+			}
+			if math.IsNaN(y) || math.IsInf(y, 0) {
+				continue
+			}
+			s.X = append(s.X, x)
+			s.Y = append(s.Y, y)
+		}
+		plot.Points = append(plot.Points, s)
+	}
+	return plot, nil
+}
+
+func savePlot(p *render.StaticPlot, file string, width, height float64) error {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".png", ".svg", ".pdf":
+	default:
+		return fmt.Errorf("unsupported plot file '%s'; use extension .png, .svg or .pdf", file)
+	}
+	if width <= 0 {
+		width = defaultPlotWidth
+	}
+	if height <= 0 {
+		height = defaultPlotHeight
+	}
+	return p.Save(file, width, height)
+}
+
+// dataColumns selects columns by name. Returns all columns except "Run" and "Ticks" if names is empty.
+func dataColumns(table *CsvTable, names []string) ([]string, []int, error) {
+	if len(names) == 0 {
+		for _, h := range table.Header {
+			if h != "Run" && h != "Ticks" {
+				names = append(names, h)
+			}
+		}
+	}
+	indices := make([]int, len(names))
+	for i, name := range names {
+		indices[i] = table.Column(name)
+		if indices[i] < 0 {
+			return nil, nil, fmt.Errorf("column '%s' not found", name)
+		}
+	}
+	return names, indices, nil
+}
+
+// groupRuns groups runs by parameter set, in order of first appearance.
+func groupRuns(runs []string, sets map[string]string) ([]string, map[string][]string) {
+	groups := []string{}
+	groupRuns := map[string][]string{}
+	for _, run := range runs {
+		set := sets[run]
+		if _, ok := groupRuns[set]; !ok {
+			groups = append(groups, set)
+		}
+		groupRuns[set] = append(groupRuns[set], run)
+	}
+	return groups, groupRuns
+}
+
+func parseColumns(rows [][]string, xCol, yCol int) ([]float64, []float64, error) {
+	x := make([]float64, 0, len(rows))
+	y := make([]float64, 0, len(rows))
+	for _, row := range rows {
+		xv, err := strconv.ParseFloat(row[xCol], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		yv, err := strconv.ParseFloat(row[yCol], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		x = append(x, xv)
+		y = append(y, yv)
+	}
+	return x, y, nil
+}
+
+// collectTicks collects the values of a column over runs, by tick.
+// NaN values are skipped. Returns sorted ticks and values per tick.
+func collectTicks(rows map[string][][]string, runs []string, tickCol, col int) ([]float64, [][]float64, error) {
+	byTick := map[float64][]float64{}
+	for _, run := range runs {
+		x, y, err := parseColumns(rows[run], tickCol, col)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range x {
+			if math.IsNaN(y[i]) || math.IsInf(y[i], 0) {
+				continue
+			}
+			byTick[x[i]] = append(byTick[x[i]], y[i])
+		}
+	}
+	ticks := make([]float64, 0, len(byTick))
+	for t := range byTick {
+		ticks = append(ticks, t)
+	}
+	slices.Sort(ticks)
+	values := make([][]float64, len(ticks))
+	for i, t := range ticks {
+		values[i] = byTick[t]
+	}
+	return ticks, values, nil
+}
+
+// responseRow returns the row at the given tick, or the last row if tick is nil.
+// Returns nil if there is no row for the tick.
+func responseRow(rows [][]string, tickCol int, tick *int) ([]string, error) {
+	if tick == nil {
+		return rows[len(rows)-1], nil
+	}
+	if tickCol < 0 {
+		return nil, fmt.Errorf("missing column 'Ticks'")
+	}
+	t := strconv.Itoa(*tick)
+	for _, row := range rows {
+		if row[tickCol] == t {
+			return row, nil
+		}
+	}
+	return nil, nil
+}
+
+func meanSD(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(values)-1))
+}
+
+// quantile of sorted values, with linear interpolation.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return sorted[lower]*(1-frac) + sorted[upper]*frac
+}
@@ -1,7 +1,10 @@
 package util
 
+import "github.com/mlange-42/beecs-cli/events"
+
 type Tables struct {
 	Headers [][]string
 	Data    [][][]float64
 	Index   int
+	Events  []events.Event // Events emitted during the run, in chunks like table rows.
 }
@@ -0,0 +1,214 @@
+package util
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mlange-42/ark-tools/observer"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Transform operations.
+const (
+	opRollingMean = "RollingMean" // Mean of a column over the last Window ticks.
+	opRollingSum  = "RollingSum"  // Sum of a column over the last Window ticks.
+	opDiff        = "Diff"        // Difference of a column to its value Window ticks ago.
+	opCumSum      = "CumSum"      // Cumulative sum of a column.
+	opRatio       = "Ratio"       // Ratio of a column to column Other.
+)
+
+// TransformDef defines a derived column, calculated from columns of the transformed observer.
+type TransformDef struct {
+	Name   string // Name of the derived column.
+	Op     string // Operation, one of RollingMean, RollingSum, Diff, CumSum and Ratio.
+	Column string // Column to derive from.
+	Other  string // Denominator column, for Ratio.
+	Window int    // Window size for rolling operations and lag for Diff, in ticks. Default: 1.
+}
+
+// Transformed is a row observer that adds derived columns to the columns of another row observer.
+//
+// With rolling operations, Diff or CumSum, the values of the wrapped observer are queried on every tick,
+// independent of the update interval of the table or plot. Otherwise, they are only queried when a row is requested.
+// Rolling operations and Diff are NaN until enough ticks are available.
+//
+// Columns not found in the wrapped observer's header are reported by [Transformed.Err] after initialization,
+// and the derived columns are NaN. Errors of the wrapped observer are reported as well.
+type Transformed struct {
+	Observer  observer.Row
+	Transform []TransformDef
+
+	header   []string
+	columns  [][2]int    // Column indices per transform, of Column and Other.
+	windows  []int       // Window sizes per transform.
+	stateful bool        // Whether any transform requires the values of every tick.
+	history  [][]float64 // Ring buffer of rows of the wrapped observer.
+	next     int         // Next index in the ring buffer.
+	ticks    int         // Number of observed ticks.
+	sums     []float64   // Cumulative sums per transform.
+	values   []float64
+	err      error
+}
+
+// NewTransformed wraps an observer with the given transforms.
+// Returns the observer itself if there are no transforms.
+func NewTransformed(obs observer.Row, transform []TransformDef) (observer.Row, error) {
+	if len(transform) == 0 {
+		return obs, nil
+	}
+	for _, t := range transform {
+		switch t.Op {
+		case opRollingMean, opRollingSum, opDiff, opCumSum:
+		case opRatio:
+			if t.Other == "" {
+				return nil, fmt.Errorf("transform '%s' requires column Other", t.Name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown operation '%s' in transform '%s'", t.Op, t.Name)
+		}
+		if t.Name == "" || t.Column == "" {
+			return nil, fmt.Errorf("transform with operation '%s' requires Name and Column", t.Op)
+		}
+	}
+	return &Transformed{Observer: obs, Transform: transform}, nil
+}
+
+// Initialize the observer.
+func (t *Transformed) Initialize(w *ecs.World) {
+	t.Observer.Initialize(w)
+	t.err = ObserverError(t.Observer)
+	inner := t.Observer.Header()
+
+	t.header = append([]string{}, inner...)
+	t.columns = make([][2]int, len(t.Transform))
+	t.windows = make([]int, len(t.Transform))
+	t.stateful = false
+	length := 1
+	for i, tr := range t.Transform {
+		t.windows[i] = max(tr.Window, 1)
+		t.columns[i][0] = t.columnIndex(inner, tr.Column, tr.Name)
+		if tr.Op == opRatio {
+			t.columns[i][1] = t.columnIndex(inner, tr.Other, tr.Name)
+		} else {
+			t.stateful = true
+		}
+		switch tr.Op {
+		case opRollingMean, opRollingSum:
+			length = max(length, t.windows[i])
+		case opDiff:
+			length = max(length, t.windows[i]+1)
+		}
+		t.header = append(t.header, tr.Name)
+	}
+
+	t.history = make([][]float64, length)
+	t.next = 0
+	t.ticks = 0
+	t.sums = make([]float64, len(t.Transform))
+	t.values = make([]float64, len(t.header))
+	for i := range t.values {
+		t.values[i] = math.NaN()
+	}
+}
+
+// Update the observer.
+func (t *Transformed) Update(w *ecs.World) {
+	t.Observer.Update(w)
+	t.ticks++
+	if !t.stateful || t.err != nil {
+		return
+	}
+	row := t.Observer.Values(w)
+	t.history[t.next] = append(t.history[t.next][:0], row...)
+	t.next = (t.next + 1) % len(t.history)
+
+	for i, tr := range t.Transform {
+		if tr.Op == opCumSum {
+			t.sums[i] += row[t.columns[i][0]]
+		}
+	}
+}
+
+// past returns the row observed the given number of ticks ago.
+func (t *Transformed) past(lag int) []float64 {
+	idx := (t.next - 1 - lag + 2*len(t.history)) % len(t.history)
+	return t.history[idx]
+}
+
+// columnIndex returns the index of a column of a transform in the given header.
+// Records an error and returns -1 if the column is not found.
+func (t *Transformed) columnIndex(header []string, column string, transform string) int {
+	for i, h := range header {
+		if h == column {
+			return i
+		}
+	}
+	if t.err == nil {
+		t.err = fmt.Errorf("column '%s' of transform '%s' not found in observer header %v", column, transform, header)
+	}
+	return -1
+}
+
+// Err returns the error of the last initialization, like a column not found in the wrapped observer.
+func (t *Transformed) Err() error {
+	return t.err
+}
+
+// ObserverError returns the initialization error of an observer that reports errors by an Err method,
+// like [Transformed] or observers.Expressions. Returns nil for other observers.
+func ObserverError(obs any) error {
+	if e, ok := obs.(interface{ Err() error }); ok {
+		return e.Err()
+	}
+	return nil
+}
+
+// Header of the observer.
+func (t *Transformed) Header() []string {
+	return t.header
+}
+
+// Values of the observer.
+func (t *Transformed) Values(w *ecs.World) []float64 {
+	var row []float64
+	if t.stateful {
+		if t.ticks == 0 || t.err != nil {
+			return t.values
+		}
+		row = t.past(0)
+	} else {
+		row = t.Observer.Values(w)
+	}
+	copy(t.values, row)
+	if t.err != nil {
+		return t.values
+	}
+
+	offset := len(row)
+	for i, tr := range t.Transform {
+		col, window := t.columns[i][0], t.windows[i]
+		v := math.NaN()
+		switch tr.Op {
+		case opRollingMean, opRollingSum:
+			if t.ticks >= window {
+				v = 0
+				for lag := range window {
+					v += t.past(lag)[col]
+				}
+				if tr.Op == opRollingMean {
+					v /= float64(window)
+				}
+			}
+		case opDiff:
+			if t.ticks > window {
+				v = row[col] - t.past(window)[col]
+			}
+		case opCumSum:
+			v = t.sums[i]
+		case opRatio:
+			v = row[col] / row[t.columns[i][1]]
+		}
+		t.values[offset+i] = v
+	}
+	return t.values
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// counter is a row observer with columns A = tick + 1 and B = 2, that counts queries of its values.
+type counter struct {
+	tick    int
+	queries int
+}
+
+func (c *counter) Initialize(w *ecs.World) { c.tick = 0 }
+func (c *counter) Update(w *ecs.World)     { c.tick++ }
+func (c *counter) Header() []string        { return []string{"A", "B"} }
+func (c *counter) Values(w *ecs.World) []float64 {
+	c.queries++
+	return []float64{float64(c.tick), 2}
+}
+
+func TestTransformed(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name      string
+		transform TransformDef
+		expected  []float64 // Derived values after ticks 1 to 4.
+	}{
+		{"rolling mean", TransformDef{Name: "X", Op: opRollingMean, Column: "A", Window: 2}, []float64{nan, 1.5, 2.5, 3.5}},
+		{"rolling sum", TransformDef{Name: "X", Op: opRollingSum, Column: "A", Window: 3}, []float64{nan, nan, 6, 9}},
+		{"diff", TransformDef{Name: "X", Op: opDiff, Column: "A", Window: 2}, []float64{nan, nan, 2, 2}},
+		{"cumsum", TransformDef{Name: "X", Op: opCumSum, Column: "A"}, []float64{1, 3, 6, 10}},
+		{"ratio", TransformDef{Name: "X", Op: opRatio, Column: "A", Other: "B"}, []float64{0.5, 1, 1.5, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ecs.NewWorld()
+			obs, err := NewTransformed(&counter{}, []TransformDef{tt.transform})
+			if err != nil {
+				t.Fatal(err)
+			}
+			obs.Initialize(&w)
+			if err := ObserverError(obs); err != nil {
+				t.Fatal(err)
+			}
+			if h := obs.Header(); len(h) != 3 || h[2] != "X" {
+				t.Fatalf("unexpected header %v", h)
+			}
+			for i, exp := range tt.expected {
+				obs.Update(&w)
+				v := obs.Values(&w)[2]
+				if !(v == exp || math.IsNaN(v) && math.IsNaN(exp)) {
+					t.Errorf("tick %d: expected %f, got %f", i+1, exp, v)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformedQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform TransformDef
+		queries   int
+	}{
+		{"stateful", TransformDef{Name: "X", Op: opCumSum, Column: "A"}, 10},
+		{"stateless", TransformDef{Name: "X", Op: opRatio, Column: "A", Other: "B"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ecs.NewWorld()
+			inner := &counter{}
+			obs, err := NewTransformed(inner, []TransformDef{tt.transform})
+			if err != nil {
+				t.Fatal(err)
+			}
+			obs.Initialize(&w)
+			for i := range 10 {
+				obs.Update(&w)
+				if i%5 == 4 {
+					obs.Values(&w)
+				}
+			}
+			if inner.queries != tt.queries {
+				t.Errorf("expected %d queries of the wrapped observer, got %d", tt.queries, inner.queries)
+			}
+		})
+	}
+}
+
+func TestTransformedErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform TransformDef
+		create    bool // Whether the error is already detected on creation.
+	}{
+		{"unknown op", TransformDef{Name: "X", Op: "Median", Column: "A"}, true},
+		{"no name", TransformDef{Op: opCumSum, Column: "A"}, true},
+		{"no column", TransformDef{Name: "X", Op: opCumSum}, true},
+		{"ratio without other", TransformDef{Name: "X", Op: opRatio, Column: "A"}, true},
+		{"missing column", TransformDef{Name: "X", Op: opCumSum, Column: "C"}, false},
+		{"missing other", TransformDef{Name: "X", Op: opRatio, Column: "A", Other: "C"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs, err := NewTransformed(&counter{}, []TransformDef{tt.transform})
+			if tt.create {
+				if err == nil {
+					t.Error("expected error on creation")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := ecs.NewWorld()
+			obs.Initialize(&w)
+			if ObserverError(obs) == nil {
+				t.Fatal("expected error after initialization")
+			}
+			obs.Update(&w)
+			if v := obs.Values(&w)[2]; !math.IsNaN(v) {
+				t.Errorf("expected NaN for derived column, got %f", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/mlange-42/ark-tools/observer"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs-cli/observers"
+	"github.com/mlange-42/beecs/globals"
+)
+
+// Trigger types.
+const (
+	triggerDayOfYear  = "DayOfYear"  // On a day of each year.
+	triggerBelow      = "Below"      // When a value falls below a threshold.
+	triggerAbove      = "Above"      // When a value rises above a threshold.
+	triggerExtinction = "Extinction" // On extinction of the colony.
+)
+
+// daysPerYear is the number of ticks per year.
+const daysPerYear = 365
+
+// TriggerDef defines a condition for writing a step table.
+type TriggerDef struct {
+	On        string  // Trigger type, one of DayOfYear, Below, Above and Extinction.
+	Day       int     // Day of the year for DayOfYear, starting at 0.
+	Value     string  // Expression for Below and Above, like for observer observers.Expressions.
+	Threshold float64 // Threshold for Below and Above.
+}
+
+// Triggered is a table observer that provides the table of another observer only on ticks where a trigger fires,
+// and an empty table otherwise.
+//
+// Threshold and extinction triggers fire when their condition becomes true, not on every tick it holds.
+// For runs continued from a snapshot, i.e. if the first update is not at tick 0,
+// conditions that hold at the first update don't fire, as they fired before the snapshot was taken.
+//
+// Invalid expressions are reported by [Triggered.Err] after initialization.
+type Triggered struct {
+	Observer observer.Table
+	Triggers []TriggerDef
+
+	tick    ecs.Resource[resource.Tick]
+	pop     ecs.Resource[globals.PopulationStats]
+	values  []observers.Expressions // Expressions for threshold triggers.
+	state   []bool                  // Whether the condition of a threshold trigger holds.
+	fired   bool
+	updated bool // Whether the observer was updated since initialization.
+	err     error
+}
+
+// NewTriggered wraps an observer with the given triggers.
+// Returns the observer itself if there are no triggers.
+func NewTriggered(obs observer.Table, triggers []TriggerDef) (observer.Table, error) {
+	if len(triggers) == 0 {
+		return obs, nil
+	}
+	for _, t := range triggers {
+		switch t.On {
+		case triggerDayOfYear:
+			if t.Day < 0 || t.Day >= daysPerYear {
+				return nil, fmt.Errorf("day of trigger %s must be in range [0, %d), got %d", t.On, daysPerYear, t.Day)
+			}
+		case triggerBelow, triggerAbove:
+			if t.Value == "" {
+				return nil, fmt.Errorf("trigger %s requires an expression Value", t.On)
+			}
+		case triggerExtinction:
+		default:
+			return nil, fmt.Errorf("unknown trigger type '%s'", t.On)
+		}
+	}
+	return &Triggered{Observer: obs, Triggers: triggers}, nil
+}
+
+// Initialize the observer.
+func (t *Triggered) Initialize(w *ecs.World) {
+	t.Observer.Initialize(w)
+	t.tick = ecs.NewResource[resource.Tick](w)
+	t.pop = ecs.NewResource[globals.PopulationStats](w)
+
+	t.err = ObserverError(t.Observer)
+	t.values = make([]observers.Expressions, len(t.Triggers))
+	t.state = make([]bool, len(t.Triggers))
+	for i, tr := range t.Triggers {
+		if tr.On == triggerBelow || tr.On == triggerAbove {
+			t.values[i] = observers.Expressions{Columns: []observers.Expression{{Name: tr.On, Expr: tr.Value}}}
+			t.values[i].Initialize(w)
+			if err := t.values[i].Err(); err != nil && t.err == nil {
+				t.err = fmt.Errorf("in trigger %s: %s", tr.On, err.Error())
+			}
+		}
+	}
+	t.fired = false
+	t.updated = false
+}
+
+// Err returns the error of the last initialization, like an invalid trigger expression.
+func (t *Triggered) Err() error {
+	return t.err
+}
+
+// Update the observer.
+func (t *Triggered) Update(w *ecs.World) {
+	t.Observer.Update(w)
+
+	tick := t.tick.Get().Tick
+	emit := t.updated || tick == 0
+	t.updated = true
+	t.fired = false
+	for i, tr := range t.Triggers {
+		var condition bool
+		switch tr.On {
+		case triggerDayOfYear:
+			t.fired = t.fired || tick%daysPerYear == int64(tr.Day)
+			continue
+		case triggerBelow:
+			condition = t.values[i].Values(w)[0] < tr.Threshold
+		case triggerAbove:
+			condition = t.values[i].Values(w)[0] > tr.Threshold
+		case triggerExtinction:
+			condition = t.pop.Get().TotalPopulation == 0
+		}
+		t.fired = t.fired || (emit && condition && !t.state[i])
+		t.state[i] = condition
+	}
+}
+
+// Header of the observer.
+func (t *Triggered) Header() []string {
+	return t.Observer.Header()
+}
+
+// Values of the observer. Empty if no trigger fired in the current tick.
+func (t *Triggered) Values(w *ecs.World) [][]float64 {
+	if !t.fired {
+		return nil
+	}
+	return t.Observer.Values(w)
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/globals"
+)
+
+// rows is a table observer for tests, with a single row.
+type rows struct{}
+
+func (r *rows) Initialize(w *ecs.World)         {}
+func (r *rows) Update(w *ecs.World)             {}
+func (r *rows) Header() []string                { return []string{"A"} }
+func (r *rows) Values(w *ecs.World) [][]float64 { return [][]float64{{1}} }
+
+func TestTriggered(t *testing.T) {
+	type state struct {
+		tick  int64
+		honey float64
+		pop   int
+	}
+	tests := []struct {
+		name     string
+		trigger  TriggerDef
+		states   []state
+		expected []int64
+	}{
+		{"day of year", TriggerDef{On: "DayOfYear", Day: 2},
+			[]state{{0, 1, 1}, {1, 1, 1}, {2, 1, 1}, {3, 1, 1}, {366, 1, 1}, {367, 1, 1}}, []int64{2, 367}},
+		{"below", TriggerDef{On: "Below", Value: "globals.Stores.Honey", Threshold: 5},
+			[]state{{0, 10, 1}, {1, 4, 1}, {2, 3, 1}, {3, 5, 1}, {4, 2, 1}}, []int64{1, 4}},
+		{"below at start", TriggerDef{On: "Below", Value: "globals.Stores.Honey", Threshold: 5},
+			[]state{{0, 4, 1}, {1, 3, 1}}, []int64{0}},
+		{"above", TriggerDef{On: "Above", Value: "globals.Stores.Honey * 2", Threshold: 10},
+			[]state{{0, 5, 1}, {1, 6, 1}, {2, 7, 1}, {3, 4, 1}, {4, 6, 1}}, []int64{1, 4}},
+		{"extinction", TriggerDef{On: "Extinction"},
+			[]state{{0, 1, 10}, {1, 1, 0}, {2, 1, 0}, {3, 1, 5}, {4, 1, 0}}, []int64{1, 4}},
+		{"continued", TriggerDef{On: "Below", Value: "globals.Stores.Honey", Threshold: 5},
+			[]state{{100, 3, 1}, {101, 6, 1}, {102, 2, 1}}, []int64{102}},
+		{"continued day of year", TriggerDef{On: "DayOfYear", Day: 0},
+			[]state{{365, 1, 1}, {366, 1, 1}}, []int64{365}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ecs.NewWorld()
+			tick := resource.Tick{}
+			stores := globals.Stores{}
+			pop := globals.PopulationStats{}
+			ecs.AddResource(&w, &tick)
+			ecs.AddResource(&w, &stores)
+			ecs.AddResource(&w, &pop)
+
+			obs, err := NewTriggered(&rows{}, []TriggerDef{tt.trigger})
+			if err != nil {
+				t.Fatal(err)
+			}
+			obs.Initialize(&w)
+			if err := ObserverError(obs); err != nil {
+				t.Fatal(err)
+			}
+			fired := []int64{}
+			for _, s := range tt.states {
+				tick.Tick, stores.Honey, pop.TotalPopulation = s.tick, s.honey, s.pop
+				obs.Update(&w)
+				if len(obs.Values(&w)) > 0 {
+					fired = append(fired, s.tick)
+				}
+			}
+			if !slices.Equal(fired, tt.expected) {
+				t.Errorf("expected trigger at ticks %v, got %v", tt.expected, fired)
+			}
+		})
+	}
+}
+
+func TestTriggeredErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger TriggerDef
+	}{
+		{"unknown type", TriggerDef{On: "Sometimes"}},
+		{"negative day", TriggerDef{On: "DayOfYear", Day: -1}},
+		{"day out of range", TriggerDef{On: "DayOfYear", Day: 365}},
+		{"missing value", TriggerDef{On: "Below", Threshold: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTriggered(&rows{}, []TriggerDef{tt.trigger}); err == nil {
+				t.Error("expected error for invalid trigger")
+			}
+		})
+	}
+
+	w := ecs.NewWorld()
+	ecs.AddResource(&w, &resource.Tick{})
+	obs, err := NewTriggered(&rows{}, []TriggerDef{{On: "Above", Value: "globals.Missing.Value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obs.Initialize(&w)
+	if ObserverError(obs) == nil {
+		t.Error("expected error for invalid trigger expression")
+	}
+}
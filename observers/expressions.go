@@ -0,0 +1,83 @@
+// Package observers provides additional observers, configured via ObserverConfig.
+package observers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Expression is a named expression for a column of [Expressions].
+type Expression struct {
+	Name string // Column name.
+	Expr string // Expression to evaluate, like "globals.Stores.Honey / params.EnergyContent.Honey / 1000".
+}
+
+// Expressions is a row observer with columns defined by arithmetic expressions.
+//
+// Expressions support numbers, the operators +, -, * and /, and parentheses.
+// Resource fields are referenced by the resource's type name and the field path,
+// like "globals.Stores.Honey" or "params.EnergyContent.Honey".
+// Fields may be of any numeric or boolean type.
+// Entity counts are given by "count(...)" with a list of component type names,
+// like "count(comp.Age, comp.Milage)".
+//
+// Resources and components are resolved on initialization.
+// They must be present in the world at that time.
+// Invalid expressions are reported by [Expressions.Err] after initialization,
+// and all values are NaN.
+type Expressions struct {
+	Columns []Expression // Columns of the observer.
+
+	header []string
+	nodes  []node
+	values []float64
+	err    error
+}
+
+// Initialize the observer.
+func (e *Expressions) Initialize(w *ecs.World) {
+	e.header = make([]string, len(e.Columns))
+	e.nodes = make([]node, len(e.Columns))
+	e.values = make([]float64, len(e.Columns))
+	e.err = nil
+	for i, col := range e.Columns {
+		e.header[i] = col.Name
+		n, err := parse(col.Expr, w)
+		if err != nil {
+			if e.err == nil {
+				e.err = fmt.Errorf("error in expression for column '%s': %s", col.Name, err.Error())
+			}
+			continue
+		}
+		e.nodes[i] = n
+	}
+}
+
+// Err returns the error of the last initialization, like an invalid expression.
+func (e *Expressions) Err() error {
+	return e.err
+}
+
+// Update the observer.
+func (e *Expressions) Update(w *ecs.World) {}
+
+// Header of the observer.
+func (e *Expressions) Header() []string {
+	return e.header
+}
+
+// Values of the observer.
+func (e *Expressions) Values(w *ecs.World) []float64 {
+	if e.err != nil {
+		for i := range e.values {
+			e.values[i] = math.NaN()
+		}
+		return e.values
+	}
+	for i, n := range e.nodes {
+		e.values[i] = n.eval(w)
+	}
+	return e.values
+}
@@ -0,0 +1,309 @@
+package observers
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// node of an expression tree.
+type node interface {
+	eval(w *ecs.World) float64
+}
+
+type number float64
+
+func (n number) eval(w *ecs.World) float64 {
+	return float64(n)
+}
+
+type negate struct {
+	operand node
+}
+
+func (n *negate) eval(w *ecs.World) float64 {
+	return -n.operand.eval(w)
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (n *binary) eval(w *ecs.World) float64 {
+	l, r := n.left.eval(w), n.right.eval(w)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		return l / r
+	}
+}
+
+// field of a resource, given by the resource ID and the field index path.
+type field struct {
+	resource ecs.ResID
+	index    []int
+}
+
+func (n *field) eval(w *ecs.World) float64 {
+	v := reflect.Indirect(reflect.ValueOf(w.Resources().Get(n.resource))).FieldByIndex(n.index)
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// count of entities with the given components.
+type count struct {
+	filter ecs.UnsafeFilter
+}
+
+func (n *count) eval(w *ecs.World) float64 {
+	query := n.filter.Query()
+	cnt := query.Count()
+	query.Close()
+	return float64(cnt)
+}
+
+// parser for expressions, by recursive descent.
+type parser struct {
+	world  *ecs.World
+	tokens []string
+	pos    int
+}
+
+// parse an expression and resolve its resources and components in the given world.
+func parse(expr string, w *ecs.World) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{world: w, tokens: tokens}
+	n, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' in expression '%s'", p.tokens[p.pos], expr)
+	}
+	return n, nil
+}
+
+// tokenize splits an expression into numbers, identifiers and single-character symbols.
+func tokenize(expr string) ([]string, error) {
+	tokens := []string{}
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, string(r))
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' ||
+				runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' in expression '%s'", r, expr)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) expect(token string) error {
+	if p.peek() != token {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected '%s', got end of expression", token)
+		}
+		return fmt.Errorf("expected '%s', got '%s'", token, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// expression := term (('+' | '-') term)*
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+// term := unary (('*' | '/') unary)*
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+// unary := '-' unary | primary
+func (p *parser) unary() (node, error) {
+	if p.peek() == "-" {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negate{operand: operand}, nil
+	}
+	return p.primary()
+}
+
+// primary := number | '(' expression ')' | 'count' '(' component (',' component)* ')' | field
+func (p *parser) primary() (node, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch {
+	case token == "(":
+		n, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case token == "count" && p.peek() == "(":
+		p.pos++
+		return p.count()
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		v, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", token)
+		}
+		return number(v), nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		return resolveField(p.world, token)
+	}
+	return nil, fmt.Errorf("unexpected '%s'", token)
+}
+
+// count parses the component list of a count, after the opening parenthesis.
+func (p *parser) count() (node, error) {
+	components := map[string]ecs.ID{}
+	for _, id := range ecs.ComponentIDs(p.world) {
+		if info, ok := ecs.ComponentInfo(p.world, id); ok {
+			components[info.Type.String()] = id
+		}
+	}
+
+	ids := []ecs.ID{}
+	for {
+		name := p.peek()
+		id, ok := components[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown component '%s' in count", name)
+		}
+		ids = append(ids, id)
+		p.pos++
+		if p.peek() != "," {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &count{filter: ecs.NewUnsafeFilter(p.world, ids...)}, nil
+}
+
+// resolveField resolves a path like "globals.Stores.Honey" to a resource and a numeric field.
+// The longest prefix of the path that is the type name of a resource is used.
+func resolveField(w *ecs.World, path string) (node, error) {
+	resources := map[string]ecs.ResID{}
+	for _, id := range ecs.ResourceIDs(w) {
+		if tp, ok := ecs.ResourceType(w, id); ok {
+			resources[tp.String()] = id
+		}
+	}
+
+	parts := strings.Split(path, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		id, ok := resources[strings.Join(parts[:i], ".")]
+		if !ok {
+			continue
+		}
+		tp, _ := ecs.ResourceType(w, id)
+		index := []int{}
+		for _, name := range parts[i:] {
+			if tp.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("can't access field '%s' of non-struct type %s in '%s'", name, tp.String(), path)
+			}
+			f, ok := tp.FieldByName(name)
+			if !ok || !f.IsExported() {
+				return nil, fmt.Errorf("type %s has no exported field '%s' in '%s'", tp.String(), name, path)
+			}
+			index = append(index, f.Index...)
+			tp = f.Type
+		}
+		switch tp.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			return &field{resource: id, index: index}, nil
+		}
+		return nil, fmt.Errorf("field '%s' is of non-numeric type %s", path, tp.String())
+	}
+	return nil, fmt.Errorf("no resource found for '%s'", path)
+}
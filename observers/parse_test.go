@@ -0,0 +1,149 @@
+package observers
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+type testStores struct {
+	Honey  float64
+	Larvae int32
+	Queen  bool
+	Name   string
+	Inner  struct{ Pollen float32 }
+	hidden float64
+}
+
+type testAge struct{ Age int }
+type testMilage struct{ Milage float32 }
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		tokens []string
+		err    bool
+	}{
+		{"empty", "", []string{}, false},
+		{"arithmetic", "1+2 * (3-4)/5", []string{"1", "+", "2", "*", "(", "3", "-", "4", ")", "/", "5"}, false},
+		{"exponent", "1.5e-3 + .5E2", []string{"1.5e-3", "+", ".5E2"}, false},
+		{"field", "globals.Stores.Honey / 1000", []string{"globals.Stores.Honey", "/", "1000"}, false},
+		{"count", "count(comp.Age, comp.Milage)", []string{"count", "(", "comp.Age", ",", "comp.Milage", ")"}, false},
+		{"invalid", "1 % 2", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.expr)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error, got tokens %q", tokens)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tokens, tt.tokens) {
+				t.Errorf("expected tokens %q, got %q", tt.tokens, tokens)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	w := ecs.NewWorld()
+	stores := testStores{Honey: 1500, Larvae: 20, Queen: true}
+	stores.Inner.Pollen = 2.5
+	ecs.AddResource(&w, &stores)
+
+	ages := ecs.NewMap1[testAge](&w)
+	both := ecs.NewMap2[testAge, testMilage](&w)
+	for range 3 {
+		ages.NewEntity(&testAge{})
+	}
+	for range 2 {
+		both.NewEntity(&testAge{}, &testMilage{})
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		value float64
+		err   bool
+	}{
+		{"number", "42", 42, false},
+		{"precedence", "1 + 2 * 3", 7, false},
+		{"parentheses", "(1 + 2) * 3", 9, false},
+		{"left associative", "8 - 4 - 2", 2, false},
+		{"division", "1 / 4", 0.25, false},
+		{"negation", "--2 * -3", -6, false},
+		{"float field", "observers.testStores.Honey / 1000", 1.5, false},
+		{"int field", "observers.testStores.Larvae", 20, false},
+		{"bool field", "observers.testStores.Queen", 1, false},
+		{"nested field", "observers.testStores.Inner.Pollen", 2.5, false},
+		{"count", "count(observers.testAge)", 5, false},
+		{"count multiple", "count(observers.testAge, observers.testMilage)", 2, false},
+		{"unknown resource", "globals.Stores.Honey", 0, true},
+		{"unknown field", "observers.testStores.Nectar", 0, true},
+		{"unexported field", "observers.testStores.hidden", 0, true},
+		{"non-numeric field", "observers.testStores.Name", 0, true},
+		{"struct field", "observers.testStores.Inner", 0, true},
+		{"unknown component", "count(observers.testStores)", 0, true},
+		{"unclosed count", "count(observers.testAge", 0, true},
+		{"unclosed", "(1 + 2", 0, true},
+		{"trailing", "1 2", 0, true},
+		{"incomplete", "1 +", 0, true},
+		{"invalid number", "1.2.3", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parse(tt.expr, &w)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error for '%s'", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := n.eval(&w); math.Abs(v-tt.value) > 1e-9 {
+				t.Errorf("expected %f, got %f", tt.value, v)
+			}
+		})
+	}
+}
+
+func TestExpressionsErr(t *testing.T) {
+	w := ecs.NewWorld()
+	ecs.AddResource(&w, &testStores{Honey: 1500})
+
+	obs := Expressions{Columns: []Expression{
+		{Name: "Honey", Expr: "observers.testStores.Honey"},
+		{Name: "Nectar", Expr: "observers.testStores.Nectar"},
+	}}
+	obs.Initialize(&w)
+	if obs.Err() == nil {
+		t.Fatal("expected error for invalid expression")
+	}
+	if !slices.Equal(obs.Header(), []string{"Honey", "Nectar"}) {
+		t.Errorf("unexpected header %v", obs.Header())
+	}
+	for _, v := range obs.Values(&w) {
+		if !math.IsNaN(v) {
+			t.Errorf("expected NaN values, got %v", obs.Values(&w))
+		}
+	}
+
+	obs.Columns = obs.Columns[:1]
+	obs.Initialize(&w)
+	if err := obs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v := obs.Values(&w); v[0] != 1500 {
+		t.Errorf("expected value 1500, got %v", v)
+	}
+}
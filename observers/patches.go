@@ -0,0 +1,92 @@
+package observers
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/comp"
+)
+
+// defaultPatchTypes are the component type names of the patch types of beecs.
+var defaultPatchTypes = []string{"comp.ConstantPatch", "comp.SeasonalPatch", "comp.ScriptedPatch"}
+
+// Patches is a table observer with one row per flower patch, for long-format output in step tables.
+//
+// Columns are the patch entity ID, coordinates, distance to the colony, patch type,
+// current nectar and pollen, and the number of nectar and pollen visits.
+// Rows are sorted by patch ID.
+//
+// The patch type is the index of the first of the component types in Types the patch has, or -1 if none.
+type Patches struct {
+	Types []string // Component type names of patch types. Default: comp.ConstantPatch, comp.SeasonalPatch, comp.ScriptedPatch.
+
+	filter  *ecs.Filter4[comp.Coords, comp.PatchProperties, comp.Resource, comp.Visits]
+	typeIDs []ecs.ID
+	hasType []bool
+	values  [][]float64
+}
+
+// Initialize the observer.
+func (p *Patches) Initialize(w *ecs.World) {
+	types := p.Types
+	if len(types) == 0 {
+		types = defaultPatchTypes
+	}
+	components := map[string]ecs.ID{}
+	for _, id := range ecs.ComponentIDs(w) {
+		if info, ok := ecs.ComponentInfo(w, id); ok {
+			components[info.Type.String()] = id
+		}
+	}
+	// Types not registered in the world are kept, to preserve indices.
+	p.typeIDs = make([]ecs.ID, len(types))
+	p.hasType = make([]bool, len(types))
+	for i, name := range types {
+		p.typeIDs[i], p.hasType[i] = components[name]
+	}
+
+	p.filter = ecs.NewFilter4[comp.Coords, comp.PatchProperties, comp.Resource, comp.Visits](w)
+	p.values = nil
+}
+
+// Update the observer.
+func (p *Patches) Update(w *ecs.World) {}
+
+// Header of the observer.
+func (p *Patches) Header() []string {
+	return []string{"Patch", "X", "Y", "DistToColony", "Type", "Nectar", "Pollen", "NectarVisits", "PollenVisits"}
+}
+
+// Values of the observer.
+func (p *Patches) Values(w *ecs.World) [][]float64 {
+	p.values = p.values[:0]
+	query := p.filter.Query()
+	for query.Next() {
+		coords, props, res, visits := query.Get()
+		e := query.Entity()
+		p.values = append(p.values, []float64{
+			float64(e.ID()),
+			coords.X, coords.Y,
+			props.DistToColony,
+			float64(p.patchType(w, e)),
+			res.Nectar, res.Pollen,
+			float64(visits.Nectar), float64(visits.Pollen),
+		})
+	}
+	slices.SortFunc(p.values, func(a, b []float64) int {
+		return cmp.Compare(a[0], b[0])
+	})
+	return p.values
+}
+
+// patchType returns the index of the patch type of an entity, or -1.
+func (p *Patches) patchType(w *ecs.World, e ecs.Entity) int {
+	u := w.Unsafe()
+	for i, id := range p.typeIDs {
+		if p.hasType[i] && u.Has(e, id) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,53 @@
+package observers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/comp"
+)
+
+type testConstantPatch struct{}
+type testSeasonalPatch struct{}
+
+func TestPatches(t *testing.T) {
+	w := ecs.NewWorld()
+	constant := ecs.NewMap5[comp.Coords, comp.PatchProperties, comp.Resource, comp.Visits, testConstantPatch](&w)
+	seasonal := ecs.NewMap5[comp.Coords, comp.PatchProperties, comp.Resource, comp.Visits, testSeasonalPatch](&w)
+	untyped := ecs.NewMap4[comp.Coords, comp.PatchProperties, comp.Resource, comp.Visits](&w)
+
+	// Patches are created in different archetypes, so that query order differs from ID order.
+	e1 := seasonal.NewEntity(&comp.Coords{X: 1, Y: 2}, &comp.PatchProperties{DistToColony: 10},
+		&comp.Resource{Nectar: 5, Pollen: 6}, &comp.Visits{Nectar: 7, Pollen: 8}, &testSeasonalPatch{})
+	e2 := constant.NewEntity(&comp.Coords{X: 3, Y: 4}, &comp.PatchProperties{DistToColony: 20},
+		&comp.Resource{Nectar: 1, Pollen: 2}, &comp.Visits{Nectar: 3, Pollen: 4}, &testConstantPatch{})
+	e3 := seasonal.NewEntity(&comp.Coords{}, &comp.PatchProperties{},
+		&comp.Resource{}, &comp.Visits{}, &testSeasonalPatch{})
+	e4 := untyped.NewEntity(&comp.Coords{}, &comp.PatchProperties{}, &comp.Resource{}, &comp.Visits{})
+	// Entities without all patch components are not reported.
+	ecs.NewMap1[comp.Coords](&w).NewEntity(&comp.Coords{})
+
+	obs := Patches{Types: []string{"observers.testConstantPatch", "observers.testSeasonalPatch", "observers.testMissingPatch"}}
+	obs.Initialize(&w)
+	obs.Update(&w)
+
+	if len(obs.Header()) != 9 {
+		t.Fatalf("expected 9 columns, got %v", obs.Header())
+	}
+	expected := [][]float64{
+		{float64(e1.ID()), 1, 2, 10, 1, 5, 6, 7, 8},
+		{float64(e2.ID()), 3, 4, 20, 0, 1, 2, 3, 4},
+		{float64(e3.ID()), 0, 0, 0, 1, 0, 0, 0, 0},
+		{float64(e4.ID()), 0, 0, 0, -1, 0, 0, 0, 0},
+	}
+	values := obs.Values(&w)
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), len(values))
+	}
+	for i, row := range expected {
+		if !slices.Equal(values[i], row) {
+			t.Errorf("expected row %v, got %v", row, values[i])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+var hooksRegistry = map[string]reflect.Type{}
+
+// Hook is called at a certain point of each run.
+//
+// It receives the index of the run, the run's random seed and the model's world.
+// Returning an error aborts the experiment.
+type Hook interface {
+	Run(run int, seed int, world *ecs.World) error
+}
+
+// HookPoint is a point in the run pipeline at which hooks are called.
+type HookPoint string
+
+// Hook points, in the order in which they are called.
+const (
+	AfterSetup      HookPoint = "AfterSetup"      // After setting up the model's world and systems.
+	AfterValues     HookPoint = "AfterValues"     // After applying the experiment's parameter values.
+	AfterOverwrites HookPoint = "AfterOverwrites" // After applying parameter overwrites.
+	BeforeRun       HookPoint = "BeforeRun"       // Before running the model, after adding all observers.
+	AfterFinish     HookPoint = "AfterFinish"     // After the run has finished.
+)
+
+// HookPoints lists all hook points, in the order in which they are called.
+var HookPoints = []HookPoint{AfterSetup, AfterValues, AfterOverwrites, BeforeRun, AfterFinish}
+
+// RegisterHook registers a hook type, so that it can be used by its type name in the Hooks of an experiment.
+// The type must implement [Hook] with a pointer receiver.
+// Panics if the type does not implement [Hook], or if a hook with the same type name is already registered.
+func RegisterHook[T any]() {
+	tp := reflect.TypeOf((*T)(nil)).Elem()
+	if _, ok := hooksRegistry[tp.String()]; ok {
+		panic(fmt.Sprintf("there is already a hook with type name '%s' registered", tp.String()))
+	}
+	if !reflect.PointerTo(tp).Implements(reflect.TypeFor[Hook]()) {
+		panic(fmt.Sprintf("type '%s' does not implement the Hook interface", tp.String()))
+	}
+	hooksRegistry[tp.String()] = tp
+}
+
+// GetHook returns the hook type registered under the given type name, and whether it was found.
+func GetHook(name string) (reflect.Type, bool) {
+	t, ok := hooksRegistry[name]
+	return t, ok
+}
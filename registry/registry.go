@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/mlange-42/ark-pixel/monitor"
+	"github.com/mlange-42/beecs-cli/observers"
 	"github.com/mlange-42/beecs-cli/view"
 	"github.com/mlange-42/beecs/obs"
 	"github.com/mlange-42/beecs/registry"
@@ -26,10 +27,14 @@ func init() {
 	RegisterObserver[obs.AgeStructure]()
 	RegisterObserver[obs.ForagingStats]()
 
+	RegisterObserver[observers.Expressions]()
+	RegisterObserver[observers.Patches]()
+
 	RegisterDrawer[monitor.Monitor]()
 	RegisterDrawer[monitor.Resources]()
 	RegisterDrawer[monitor.Systems]()
 	RegisterDrawer[view.Foraging]()
+	RegisterDrawer[view.AgeStructure]()
 
 	//RegisterResource[...]()
 
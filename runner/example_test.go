@@ -0,0 +1,45 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mlange-42/beecs-cli/runner"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/params"
+)
+
+func ExampleExperiment_Run() {
+	exp := runner.Experiment{
+		Parameters: &params.CustomParams{Parameters: params.Default()},
+		Runs:       2,
+		Seed:       123,
+		Design: runner.Design{
+			Fork: &runner.ForkDef{
+				Tick: 365,
+				Treatments: []runner.TreatmentDef{
+					{Name: "Control"},
+					{Name: "SlowFlight", Parameters: []experiment.ParameterValue{
+						{Parameter: "params.Foragers.FlightVelocity", Value: 5.0},
+					}},
+				},
+			},
+		},
+		Observers: runner.Observers{
+			Tables: []runner.TableDef{{
+				Observer:       "observers.Expressions",
+				ObserverConfig: json.RawMessage(`{"Columns": [{"Name": "Honey", "Expr": "globals.Stores.Honey"}]}`),
+			}},
+		},
+		SaveSnapshot: "snapshots/year-2.json",
+		SnapshotTick: 730,
+	}
+	err := exp.Run(context.Background(), func(tables *runner.Tables) error {
+		fmt.Println("run", tables.Index)
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package runner provides a Go API for running beecs experiments programmatically,
+// e.g. for embedding them into services or other tools.
+//
+// Experiments are defined by values instead of files, and table output is passed to a callback
+// instead of being written to CSV files. Live plots and views are not supported.
+package runner
+
+import (
+	"context"
+
+	"github.com/mlange-42/ark-tools/app"
+	"github.com/mlange-42/beecs-cli/internal/run"
+	"github.com/mlange-42/beecs-cli/internal/snapshot"
+	"github.com/mlange-42/beecs-cli/internal/util"
+	"github.com/mlange-42/beecs/experiment"
+	"github.com/mlange-42/beecs/params"
+)
+
+// Tables holds table output of a run.
+//
+// Index is the index of the run. Headers and Data have one entry per table.
+// The first table holds the run's parameters, followed by the tables in the order
+// of [Observers] Tables and StepTables.
+// Each table row starts with the run index and the tick, except for rows of the parameters table.
+type Tables = util.Tables
+
+// Observers defines the observers for table output, like in the observers file.
+// Live plots, views and file names are ignored.
+type Observers = util.ObserversDef
+
+// TableDef defines a table with one row per update, for [Observers].
+type TableDef = util.TableDef
+
+// TransformDef defines a derived column of a [TableDef], like a rolling mean or a cumulative sum.
+type TransformDef = util.TransformDef
+
+// StepTableDef defines a table with a full table per update, for [Observers].
+type StepTableDef = util.StepTableDef
+
+// Design defines parameter variation and forking into treatments, like in the experiment file.
+type Design = util.ExperimentJs
+
+// ForkDef defines the forking of runs into treatments after a shared spin-up, for [Design].
+type ForkDef = util.ForkDef
+
+// TreatmentDef defines a treatment of forked runs, for [ForkDef].
+type TreatmentDef = util.TreatmentDef
+
+// HookDef defines a lifecycle hook, called at a certain point of each run.
+// Hooks must be registered with [github.com/mlange-42/beecs-cli/registry.RegisterHook].
+type HookDef = util.HookDef
+
+// Experiment runs an experiment with beecs.
+type Experiment struct {
+	Parameters params.Params               // Model parameters. Required.
+	Design     Design                      // Parameter variation. Optional, default: a single parameter set.
+	Runs       int                         // Runs per parameter set. Default: 1.
+	Seed       uint64                      // Super-seed for generating the seeds of runs.
+	Observers  Observers                   // Observers for table output.
+	Systems    []app.System                // Custom systems. Optional, default: the systems of beecs.
+	Hooks      []HookDef                   // Lifecycle hooks. Optional.
+	Overwrite  []experiment.ParameterValue // Parameter overwrites, applied after the values of the design.
+	Indices    []int                       // Indices of runs to perform. Optional, default: all.
+	Threads    int                         // Number of threads. Default: 1.
+
+	FromSnapshot string // Snapshot file to start all runs from. Optional.
+	SaveSnapshot string // File to save a snapshot of each run to, with the run index appended. Optional.
+	SnapshotTick int64  // Tick to save snapshots at, for SaveSnapshot.
+}
+
+// Run the experiment, and pass its table output to fn.
+//
+// For each run, fn is called multiple times with chunks of table rows and events,
+// and finally with the run's parameters.
+// Calls of fn are serialized, but runs may finish in arbitrary order if more than one thread is used.
+//
+// Cancelling the context terminates the experiment, and Run returns the context's error.
+// If fn returns an error, the experiment is terminated and the error is returned.
+func (e *Experiment) Run(ctx context.Context, fn func(tables *Tables) error) error {
+	runs := e.Runs
+	if runs <= 0 {
+		runs = 1
+	}
+	exp, rng, err := e.Design.Build(runs, e.Seed)
+	if err != nil {
+		return err
+	}
+	snapshots := snapshot.Options{File: e.SaveSnapshot, Tick: e.SnapshotTick}
+	if e.FromSnapshot != "" {
+		if snapshots.From, err = snapshot.ReadFile(e.FromSnapshot); err != nil {
+			return err
+		}
+	}
+	return run.Callback(ctx, e.Parameters, &exp, &e.Observers, e.Systems, e.Overwrite, e.Hooks, snapshots, e.Design.Fork, e.Threads, rng, e.Indices, fn)
+}
@@ -0,0 +1,240 @@
+package view
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"github.com/mlange-42/ark-tools/resource"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/mlange-42/beecs/obs"
+)
+
+// Margins of the age structure heatmap, in pixels.
+const (
+	ageMarginLeft   = 70.0
+	ageMarginRight  = 8.0
+	ageMarginTop    = 8.0
+	ageMarginBottom = 20.0
+	ageBandGap      = 6.0
+)
+
+// heatmapColors are the stops of the heatmap color ramp, from zero to the maximum.
+var heatmapColors = []color.RGBA{
+	{0, 0, 4, 255},
+	{87, 16, 110, 255},
+	{188, 55, 84, 255},
+	{249, 142, 9, 255},
+	{252, 255, 164, 255},
+}
+
+// AgeStructure view, showing the age distribution of eggs, larvae, pupae, in-hive bees and foragers
+// over time as a scrolling heatmap.
+//
+// Each life stage is shown as a separate band, with time on the x axis and age on the y axis.
+// Colors are scaled per stage, to the maximum number of individuals of the stage in the shown time span.
+// Data is taken from [obs.AgeStructure], with ages as rows and life stages as columns.
+//
+// All fields are optional and can be set via the view's DrawerConfig.
+type AgeStructure struct {
+	Ticks    int  // Number of columns of the heatmap. Default: 365.
+	Interval int  // Interval between columns, in ticks. Default: 1.
+	Linear   bool // Use a linear instead of a logarithmic color scale.
+
+	drawer imdraw.IMDraw
+	labels *text.Text
+
+	observer obs.AgeStructure
+	tick     ecs.Resource[resource.Tick]
+	header   []string
+	columns  [][][]float64 // Ring buffer of observed tables.
+	ticks    []int64       // Ticks of the columns in the ring buffer.
+	next     int           // Next index in the ring buffer.
+	step     int64
+}
+
+// Initialize the system
+func (a *AgeStructure) Initialize(w *ecs.World, win *opengl.Window) {
+	a.drawer = *imdraw.New(nil)
+	a.labels = text.New(pixel.V(0, 0), text.Atlas7x13)
+	a.InitializeImage(w)
+}
+
+// InitializeImage initializes the drawer for drawing to images.
+func (a *AgeStructure) InitializeImage(w *ecs.World) {
+	if a.Ticks <= 0 {
+		a.Ticks = 365
+	}
+	if a.Interval <= 0 {
+		a.Interval = 1
+	}
+	a.observer.Initialize(w)
+	a.tick = ecs.NewResource[resource.Tick](w)
+	a.header = a.observer.Header()
+	a.columns = make([][][]float64, 0, a.Ticks)
+	a.ticks = make([]int64, 0, a.Ticks)
+	a.next = 0
+	a.step = 0
+}
+
+// Update the drawer.
+func (a *AgeStructure) Update(w *ecs.World) {
+	a.observer.Update(w)
+	if a.step%int64(a.Interval) == 0 {
+		a.record(w)
+	}
+	a.step++
+}
+
+// record adds a copy of the observer's current table to the ring buffer.
+func (a *AgeStructure) record(w *ecs.World) {
+	values := a.observer.Values(w)
+	table := make([][]float64, len(values))
+	for i, row := range values {
+		table[i] = append([]float64{}, row...)
+	}
+	tick := a.tick.Get().Tick
+
+	if len(a.columns) < a.Ticks {
+		a.columns = append(a.columns, table)
+		a.ticks = append(a.ticks, tick)
+		return
+	}
+	a.columns[a.next] = table
+	a.ticks[a.next] = tick
+	a.next = (a.next + 1) % a.Ticks
+}
+
+// UpdateInputs handles input events of the previous frame update.
+func (a *AgeStructure) UpdateInputs(w *ecs.World, win *opengl.Window) {}
+
+// Draw the system
+func (a *AgeStructure) Draw(w *ecs.World, win *opengl.Window) {
+	width := win.Canvas().Bounds().W()
+	height := win.Canvas().Bounds().H()
+
+	dr := &a.drawer
+	a.labels.Clear()
+	a.draw(&imdrawCanvas{dr: dr, text: a.labels, target: win}, width, height)
+
+	dr.Draw(win)
+	dr.Clear()
+	a.labels.Draw(win, pixel.IM)
+}
+
+// DrawImage draws to a new image of the given size.
+func (a *AgeStructure) DrawImage(w *ecs.World, width, height int) image.Image {
+	c := newImageCanvas(width, height, color.Black)
+	a.draw(c, float64(width), float64(height))
+	return c.Image()
+}
+
+// column returns the table of the i-th column, from oldest to newest.
+func (a *AgeStructure) column(i int) ([][]float64, int64) {
+	if len(a.columns) < a.Ticks {
+		return a.columns[i], a.ticks[i]
+	}
+	idx := (a.next + i) % a.Ticks
+	return a.columns[idx], a.ticks[idx]
+}
+
+func (a *AgeStructure) draw(dr canvas, width, height float64) {
+	frameColor := color.RGBA{100, 100, 100, 255}
+	textColor := color.RGBA{200, 200, 200, 255}
+
+	stages := len(a.header)
+	if stages == 0 || len(a.columns) == 0 {
+		return
+	}
+	plotWidth := width - ageMarginLeft - ageMarginRight
+	bandHeight := (height-ageMarginTop-ageMarginBottom)/float64(stages) - ageBandGap
+	if plotWidth <= 0 || bandHeight <= 0 {
+		return
+	}
+
+	for s := range stages {
+		img, maxAge, maxCount := a.heatmap(s)
+
+		top := height - ageMarginTop - float64(s)*(bandHeight+ageBandGap)
+		p1 := pixel.V(ageMarginLeft, top-bandHeight)
+		p2 := pixel.V(ageMarginLeft+plotWidth, top)
+		if img != nil {
+			// Columns not yet filled are left empty.
+			filled := plotWidth * float64(len(a.columns)) / float64(a.Ticks)
+			dr.Picture(img, p1, pixel.V(p1.X+filled, p2.Y))
+		}
+		dr.Rect(p1, p2, 1, frameColor)
+
+		dr.Text(pixel.V(4, top-11), a.header[s], textColor)
+		dr.Text(pixel.V(4, top-24), fmt.Sprintf("max %.0f", maxCount), frameColor)
+		dr.Text(pixel.V(ageMarginLeft-30, p1.Y+2), "0", frameColor)
+		dr.Text(pixel.V(ageMarginLeft-30, top-11), fmt.Sprintf("%d", maxAge), frameColor)
+	}
+
+	_, first := a.column(0)
+	_, last := a.column(len(a.columns) - 1)
+	dr.Text(pixel.V(ageMarginLeft, 6), fmt.Sprintf("Tick %d", first), textColor)
+	lastLabel := fmt.Sprintf("Tick %d", last)
+	dr.Text(pixel.V(width-ageMarginRight-float64(len(lastLabel))*7, 6), lastLabel, textColor)
+}
+
+// heatmap creates the heatmap image of a life stage, with one pixel per column and age.
+// Ages beyond the maximum age with individuals in the shown time span are omitted.
+// Returns the image, the maximum age and the maximum count.
+func (a *AgeStructure) heatmap(stage int) (image.Image, int, float64) {
+	maxAge, maxCount := -1, 0.0
+	for i := range a.columns {
+		table, _ := a.column(i)
+		for age, row := range table {
+			if stage < len(row) && row[stage] > 0 {
+				maxAge = max(maxAge, age)
+				maxCount = math.Max(maxCount, row[stage])
+			}
+		}
+	}
+	if maxAge < 0 {
+		return nil, 0, 0
+	}
+
+	rows := maxAge + 1
+	img := image.NewRGBA(image.Rect(0, 0, len(a.columns), rows))
+	for i := range a.columns {
+		table, _ := a.column(i)
+		for age := 0; age < rows && age < len(table); age++ {
+			if stage >= len(table[age]) {
+				continue
+			}
+			v := table[age][stage]
+			if v <= 0 {
+				continue
+			}
+			var frac float64
+			if a.Linear {
+				frac = v / maxCount
+			} else {
+				frac = math.Log1p(v) / math.Log1p(maxCount)
+			}
+			// Image rows are top-down, ages bottom-up.
+			img.SetRGBA(i, rows-1-age, rampColor(frac))
+		}
+	}
+	return img, maxAge, maxCount
+}
+
+// rampColor interpolates the heatmap color ramp, for values between 0 and 1.
+func rampColor(frac float64) color.RGBA {
+	frac = math.Max(0, math.Min(1, frac))
+	pos := frac * float64(len(heatmapColors)-1)
+	idx := min(int(pos), len(heatmapColors)-2)
+	t := pos - float64(idx)
+	c1, c2 := heatmapColors[idx], heatmapColors[idx+1]
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+	return color.RGBA{lerp(c1.R, c2.R), lerp(c1.G, c2.G), lerp(c1.B, c2.B), 255}
+}
@@ -0,0 +1,152 @@
+package view
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"git.sr.ht/~sbinet/gg"
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"github.com/mlange-42/ark/ecs"
+	xdraw "golang.org/x/image/draw"
+)
+
+// ImageDrawer is implemented by drawers that can also draw to images, without OpenGL.
+// Used for headless rendering.
+type ImageDrawer interface {
+	InitializeImage(w *ecs.World)                          // Initialize the drawer for drawing to images.
+	Update(w *ecs.World)                                   // Update the drawer.
+	DrawImage(w *ecs.World, width, height int) image.Image // Draw to a new image of the given size.
+}
+
+// canvas abstracts the drawing primitives used by views.
+// Coordinates have their origin in the bottom left corner, with the y axis pointing up.
+// A thickness of zero means filled.
+type canvas interface {
+	Circle(center pixel.Vec, radius float64, thickness float64, color color.RGBA)
+	Arc(center pixel.Vec, radius float64, low, high float64, thickness float64, color color.RGBA)
+	Rect(p1, p2 pixel.Vec, thickness float64, color color.RGBA)
+	Text(pos pixel.Vec, s string, color color.RGBA) // Draw text, with pos at the start of the baseline.
+	Picture(img image.Image, p1, p2 pixel.Vec)      // Draw an image, stretched to the rectangle without smoothing.
+}
+
+// imdrawCanvas draws to an [imdraw.IMDraw], for OpenGL windows.
+// Text is written to a separate [text.Text], which is ignored if nil.
+// Pictures are drawn to target immediately, and thus below all shapes. They are ignored if target is nil.
+type imdrawCanvas struct {
+	dr     *imdraw.IMDraw
+	text   *text.Text
+	target pixel.Target
+}
+
+func (c *imdrawCanvas) Circle(center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
+	c.dr.Color = color
+	c.dr.Push(center)
+	c.dr.Circle(radius, thickness)
+	c.dr.Reset()
+}
+
+func (c *imdrawCanvas) Arc(center pixel.Vec, radius float64, low, high float64, thickness float64, color color.RGBA) {
+	c.dr.Color = color
+	c.dr.Push(center)
+	c.dr.CircleArc(radius, low, high, thickness)
+	c.dr.Reset()
+}
+
+func (c *imdrawCanvas) Rect(p1, p2 pixel.Vec, thickness float64, color color.RGBA) {
+	c.dr.Color = color
+	c.dr.Push(p1, p2)
+	c.dr.Rectangle(thickness)
+	c.dr.Reset()
+}
+
+func (c *imdrawCanvas) Text(pos pixel.Vec, s string, color color.RGBA) {
+	if c.text == nil {
+		return
+	}
+	c.text.Dot = pos
+	c.text.Color = color
+	_, _ = c.text.WriteString(s)
+}
+
+func (c *imdrawCanvas) Picture(img image.Image, p1, p2 pixel.Vec) {
+	if c.target == nil {
+		return
+	}
+	pic := pixel.PictureDataFromImage(img)
+	sprite := pixel.NewSprite(pic, pic.Bounds())
+	rect := pixel.R(p1.X, p1.Y, p2.X, p2.Y).Norm()
+	sprite.Draw(c.target, pixel.IM.
+		ScaledXY(pixel.ZV, pixel.V(rect.W()/pic.Bounds().W(), rect.H()/pic.Bounds().H())).
+		Moved(rect.Center()))
+}
+
+// imageCanvas draws to an image, using a software rasterizer.
+type imageCanvas struct {
+	dc     *gg.Context
+	height float64
+}
+
+func newImageCanvas(width, height int, background color.Color) *imageCanvas {
+	dc := gg.NewContext(width, height)
+	dc.SetColor(background)
+	dc.Clear()
+	return &imageCanvas{dc: dc, height: float64(height)}
+}
+
+func (c *imageCanvas) Image() image.Image {
+	return c.dc.Image()
+}
+
+func (c *imageCanvas) Circle(center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
+	c.dc.DrawCircle(center.X, c.height-center.Y, radius)
+	c.finish(thickness, color)
+}
+
+func (c *imageCanvas) Arc(center pixel.Vec, radius float64, low, high float64, thickness float64, color color.RGBA) {
+	x, y := center.X, c.height-center.Y
+	// Flipping the y axis reverses the direction of angles.
+	low, high = -high, -low
+	if thickness == 0 {
+		c.dc.MoveTo(x, y)
+		c.dc.LineTo(x+radius*math.Cos(low), y+radius*math.Sin(low))
+		c.dc.DrawArc(x, y, radius, low, high)
+		c.dc.ClosePath()
+	} else {
+		c.dc.NewSubPath()
+		c.dc.DrawArc(x, y, radius, low, high)
+	}
+	c.finish(thickness, color)
+}
+
+func (c *imageCanvas) Rect(p1, p2 pixel.Vec, thickness float64, color color.RGBA) {
+	x, y := math.Min(p1.X, p2.X), c.height-math.Max(p1.Y, p2.Y)
+	c.dc.DrawRectangle(x, y, math.Abs(p2.X-p1.X), math.Abs(p2.Y-p1.Y))
+	c.finish(thickness, color)
+}
+
+func (c *imageCanvas) finish(thickness float64, color color.RGBA) {
+	c.dc.SetColor(color)
+	if thickness == 0 {
+		c.dc.Fill()
+		return
+	}
+	c.dc.SetLineWidth(thickness)
+	c.dc.Stroke()
+}
+
+func (c *imageCanvas) Text(pos pixel.Vec, s string, color color.RGBA) {
+	c.dc.SetColor(color)
+	c.dc.DrawString(s, pos.X, c.height-pos.Y)
+}
+
+func (c *imageCanvas) Picture(img image.Image, p1, p2 pixel.Vec) {
+	rect := image.Rect(
+		int(math.Round(math.Min(p1.X, p2.X))), int(math.Round(c.height-math.Max(p1.Y, p2.Y))),
+		int(math.Round(math.Max(p1.X, p2.X))), int(math.Round(c.height-math.Min(p1.Y, p2.Y))),
+	)
+	xdraw.NearestNeighbor.Scale(c.dc.Image().(draw.Image), rect, img, img.Bounds(), draw.Over, nil)
+}
@@ -1,88 +1,322 @@
 package view
 
 import (
+	"fmt"
+	"image"
 	"image/color"
 	"math"
 
 	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/backends/opengl"
 	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
 	"github.com/mlange-42/ark/ecs"
 	"github.com/mlange-42/beecs/comp"
 	"github.com/mlange-42/beecs/globals"
 	"github.com/mlange-42/beecs/params"
 )
 
+const (
+	zoomFactor     = 1.2 // Zoom factor per mouse wheel step.
+	minZoom        = 0.25
+	maxZoom        = 100.0
+	pickRadius     = 8.0 // Radius for selecting patches, in pixels.
+	clickTolerance = 3.0 // Maximum mouse movement for a click, in pixels.
+)
+
+// Foraging view, showing the colony's stores and population, and all flower patches.
+//
+// Zoom with the mouse wheel, pan by dragging, and reset with R.
+// Hover a patch to inspect it, or click it to keep the inspection overlay.
+//
+// All fields are optional and can be set via the view's DrawerConfig.
+// Zero scales of patch resources mean auto-scaling, so that the largest patch has a bar of MaxBarHeight.
 type Foraging struct {
+	Extent           float64        // Extent of the view at zoom 1, in meters. Default: 10100.
+	Rings            []float64      // Distances of rings around the colony, in meters. Default: 1000 to 5000.
+	BarWidth         float64        // Width of resource bars, in pixels. Default: 8.
+	MaxBarHeight     float64        // Height of the largest patch resource bar when auto-scaling, in pixels. Default: 50.
+	NectarScale      float64        // Bar height of patch nectar, in pixels per liter. Default: auto.
+	PollenScale      float64        // Bar height of patch pollen, in pixels per kg. Default: auto.
+	HoneyStoreScale  float64        // Bar height of the colony's honey store, in pixels per kg. Default: 1.
+	PollenStoreScale float64        // Bar height of the colony's pollen store, in pixels per kg. Default: 40.
+	PopulationScale  float64        // Radius of population half-circles, in pixels per square root of individuals. Default: 0.2.
+	NoLegend         bool           // Hides the legend.
+	NoLabels         bool           // Hides the ring distance labels.
+	Colors           ForagingColors // Colors. Default for each color if not set.
+
 	drawer imdraw.IMDraw
+	text   *text.Text
+	labels *text.Text
 
 	stores        *globals.Stores
 	popStats      *globals.PopulationStats
 	energyContent *params.EnergyContent
 	patchFilter   ecs.Filter3[comp.Coords, comp.Resource, comp.Visits]
+	patchMap      *ecs.Map3[comp.Coords, comp.Resource, comp.Visits]
+
+	zoom     float64
+	pan      pixel.Vec
+	dragDist float64
+	hovered  ecs.Entity
+	selected ecs.Entity
+	textPos  pixel.Vec
+}
+
+// ForagingColors are the colors used by [Foraging].
+type ForagingColors struct {
+	Rings        color.RGBA // Distance rings and labels.
+	Honey        color.RGBA // Honey stores and patch nectar.
+	HoneyLimit   color.RGBA // Maximum patch nectar.
+	DecentHoney  color.RGBA // Decent honey store.
+	Pollen       color.RGBA // Pollen stores and patch pollen.
+	PollenLimit  color.RGBA // Maximum patch pollen.
+	IdealPollen  color.RGBA // Ideal pollen store.
+	Population   color.RGBA // Total population.
+	Brood        color.RGBA // Total brood.
+	Patch        color.RGBA // Patch markers.
+	NectarVisits color.RGBA // Nectar foraging visits.
+	PollenVisits color.RGBA // Pollen foraging visits.
+	Text         color.RGBA // Legend text.
+}
+
+var defaultForagingColors = ForagingColors{
+	Rings:        color.RGBA{60, 60, 60, 255},
+	Honey:        color.RGBA{180, 180, 0, 255},
+	HoneyLimit:   color.RGBA{180, 180, 80, 255},
+	DecentHoney:  color.RGBA{180, 180, 120, 255},
+	Pollen:       color.RGBA{180, 0, 180, 255},
+	PollenLimit:  color.RGBA{180, 80, 180, 255},
+	IdealPollen:  color.RGBA{180, 120, 180, 255},
+	Population:   color.RGBA{128, 128, 128, 255},
+	Brood:        color.RGBA{230, 230, 230, 255},
+	Patch:        color.RGBA{128, 128, 128, 255},
+	NectarVisits: color.RGBA{180, 180, 80, 255},
+	PollenVisits: color.RGBA{180, 80, 180, 255},
+	Text:         color.RGBA{200, 200, 200, 255},
 }
 
 // Initialize the system
 func (f *Foraging) Initialize(w *ecs.World, win *opengl.Window) {
 	f.drawer = *imdraw.New(nil)
+	f.text = text.New(pixel.V(0, 0), text.Atlas7x13)
+	f.labels = text.New(pixel.V(0, 0), text.Atlas7x13)
+	f.InitializeImage(w)
+}
 
+// InitializeImage initializes the drawer for drawing to images.
+func (f *Foraging) InitializeImage(w *ecs.World) {
 	f.stores = ecs.GetResource[globals.Stores](w)
 	f.popStats = ecs.GetResource[globals.PopulationStats](w)
 	f.energyContent = ecs.GetResource[params.EnergyContent](w)
 	f.patchFilter = *ecs.NewFilter3[comp.Coords, comp.Resource, comp.Visits](w)
+	f.patchMap = ecs.NewMap3[comp.Coords, comp.Resource, comp.Visits](w)
+	f.zoom = 1
+	f.pan = pixel.ZV
+	f.setDefaults()
+}
+
+func (f *Foraging) setDefaults() {
+	if f.Extent <= 0 {
+		f.Extent = 10_100
+	}
+	if f.Rings == nil {
+		f.Rings = []float64{1000, 2000, 3000, 4000, 5000}
+	}
+	if f.BarWidth <= 0 {
+		f.BarWidth = 8
+	}
+	if f.MaxBarHeight <= 0 {
+		f.MaxBarHeight = 50
+	}
+	if f.HoneyStoreScale <= 0 {
+		f.HoneyStoreScale = 1
+	}
+	if f.PollenStoreScale <= 0 {
+		f.PollenStoreScale = 40
+	}
+	if f.PopulationScale <= 0 {
+		f.PopulationScale = 0.2
+	}
+
+	colors := []*color.RGBA{
+		&f.Colors.Rings, &f.Colors.Honey, &f.Colors.HoneyLimit, &f.Colors.DecentHoney,
+		&f.Colors.Pollen, &f.Colors.PollenLimit, &f.Colors.IdealPollen, &f.Colors.Population, &f.Colors.Brood, &f.Colors.Patch, &f.Colors.NectarVisits, &f.Colors.PollenVisits, &f.Colors.Text,
+	}
+	defaults := []color.RGBA{
+		defaultForagingColors.Rings, defaultForagingColors.Honey, defaultForagingColors.HoneyLimit, defaultForagingColors.DecentHoney,
+		defaultForagingColors.Pollen, defaultForagingColors.PollenLimit, defaultForagingColors.IdealPollen, defaultForagingColors.Population,
+		defaultForagingColors.Brood, defaultForagingColors.Patch, defaultForagingColors.NectarVisits,
+		defaultForagingColors.PollenVisits, defaultForagingColors.Text,
+	}
+	for i, c := range colors {
+		if *c == (color.RGBA{}) {
+			*c = defaults[i]
+		}
+	}
 }
 
 // Update the drawer.
 func (f *Foraging) Update(w *ecs.World) {}
 
 // UpdateInputs handles input events of the previous frame update.
-func (f *Foraging) UpdateInputs(w *ecs.World, win *opengl.Window) {}
+func (f *Foraging) UpdateInputs(w *ecs.World, win *opengl.Window) {
+	width := win.Canvas().Bounds().W()
+	height := win.Canvas().Bounds().H()
+	mouse := win.MousePosition()
+
+	if win.JustPressed(pixel.KeyR) {
+		f.zoom = 1
+		f.pan = pixel.ZV
+	}
+
+	if scroll := win.MouseScroll().Y; scroll != 0 {
+		center, _ := f.transform(width, height)
+		zoom := math.Max(minZoom, math.Min(maxZoom, f.zoom*math.Pow(zoomFactor, scroll)))
+		// Keep the point under the mouse cursor in place.
+		f.pan = f.pan.Add(mouse.Sub(center).Scaled(1 - zoom/f.zoom))
+		f.zoom = zoom
+	}
+
+	if win.JustPressed(pixel.MouseButtonLeft) {
+		f.dragDist = 0
+	}
+	if win.Pressed(pixel.MouseButtonLeft) {
+		delta := mouse.Sub(win.MousePreviousPosition())
+		f.pan = f.pan.Add(delta)
+		f.dragDist += delta.Len()
+	}
+
+	f.hovered = ecs.Entity{}
+	if win.MouseInsideWindow() {
+		f.hovered = f.pick(mouse, width, height)
+	}
+	if win.JustReleased(pixel.MouseButtonLeft) && f.dragDist < clickTolerance {
+		f.selected = f.hovered
+	}
+}
 
 // Draw the system
 func (f *Foraging) Draw(w *ecs.World, win *opengl.Window) {
 	width := win.Canvas().Bounds().W()
 	height := win.Canvas().Bounds().H()
 
-	dMax := 10_100.0
+	dr := &f.drawer
+	f.labels.Clear()
+	f.draw(&imdrawCanvas{dr: dr, text: f.labels}, width, height)
 
-	scale := math.Min(width/dMax, height/dMax)
+	if !f.selected.IsZero() && !w.Alive(f.selected) {
+		f.selected = ecs.Entity{}
+	}
+	patch := f.selected
+	if !f.hovered.IsZero() && w.Alive(f.hovered) {
+		patch = f.hovered
+	}
+	if !patch.IsZero() {
+		f.drawInspection(&imdrawCanvas{dr: dr}, patch, width, height)
+	}
 
-	cx := width / 2
-	cy := height / 2
-	barWidth := 8.0
-	barHeight := 2.0
+	dr.Draw(win)
+	dr.Clear()
+	f.labels.Draw(win, pixel.IM)
 
-	dr := &f.drawer
+	if !patch.IsZero() {
+		f.text.Draw(win, pixel.IM.Moved(f.textPos))
+	}
+}
 
-	// Distance circles
-	drawCircle(dr, pixel.V(cx, cy), 1000*scale, 1, color.RGBA{60, 60, 60, 255})
-	drawCircle(dr, pixel.V(cx, cy), 2000*scale, 1, color.RGBA{60, 60, 60, 255})
-	drawCircle(dr, pixel.V(cx, cy), 3000*scale, 1, color.RGBA{60, 60, 60, 255})
-	drawCircle(dr, pixel.V(cx, cy), 4000*scale, 1, color.RGBA{60, 60, 60, 255})
-	drawCircle(dr, pixel.V(cx, cy), 5000*scale, 1, color.RGBA{60, 60, 60, 255})
+// DrawImage draws to a new image of the given size.
+func (f *Foraging) DrawImage(w *ecs.World, width, height int) image.Image {
+	c := newImageCanvas(width, height, color.Black)
+	f.draw(c, float64(width), float64(height))
+	return c.Image()
+}
+
+// transform returns the screen position of the colony, and the scale in pixels per meter.
+func (f *Foraging) transform(width, height float64) (pixel.Vec, float64) {
+	scale := math.Min(width/f.Extent, height/f.Extent) * f.zoom
+	return pixel.V(width/2, height/2).Add(f.pan), scale
+}
+
+// pick returns the patch closest to the given screen position, within the pick radius.
+func (f *Foraging) pick(pos pixel.Vec, width, height float64) ecs.Entity {
+	center, scale := f.transform(width, height)
+	closest := ecs.Entity{}
+	minDist := pickRadius
+
+	query := f.patchFilter.Query()
+	for query.Next() {
+		coords, _, _ := query.Get()
+		dist := center.Add(pixel.V(coords.X, coords.Y).Scaled(scale)).To(pos).Len()
+		if dist <= minDist {
+			closest = query.Entity()
+			minDist = dist
+		}
+	}
+	return closest
+}
+
+// drawInspection highlights a patch and prepares the text of the inspection overlay.
+func (f *Foraging) drawInspection(dr canvas, patch ecs.Entity, width, height float64) {
+	coords, res, vis := f.patchMap.Get(patch)
+	center, scale := f.transform(width, height)
+	pos := center.Add(pixel.V(coords.X, coords.Y).Scaled(scale))
+	dr.Circle(pos, pickRadius, 1, color.RGBA{255, 255, 255, 255})
+
+	f.text.Clear()
+	fmt.Fprintf(f.text, "Patch %d\n", patch.ID())
+	fmt.Fprintf(f.text, "Coords    %.0f, %.0f\n", coords.X, coords.Y)
+	fmt.Fprintf(f.text, "Distance  %.0f m\n", math.Hypot(coords.X, coords.Y))
+	fmt.Fprintf(f.text, "Nectar    %.4g / %.4g\n", res.Nectar, res.MaxNectar)
+	fmt.Fprintf(f.text, "Pollen    %.4g / %.4g\n", res.Pollen, res.MaxPollen)
+	fmt.Fprintf(f.text, "Visits    %d nectar, %d pollen", vis.Nectar, vis.Pollen)
+
+	// Place the overlay at the top left corner.
+	bounds := f.text.Bounds()
+	margin := 6.0
+	offset := pixel.V(margin-bounds.Min.X, height-margin-bounds.Max.Y)
+	box := bounds.Moved(offset)
+	dr.Rect(box.Min.Sub(pixel.V(margin/2, margin/2)), box.Max.Add(pixel.V(margin/2, margin/2)), 0, color.RGBA{30, 30, 30, 230})
+	f.textPos = offset
+}
+
+func (f *Foraging) draw(dr canvas, width, height float64) {
+	center, scale := f.transform(width, height)
+	cx, cy := center.X, center.Y
+	barWidth := f.BarWidth
+	col := &f.Colors
+
+	// Distance rings
+	for _, r := range f.Rings {
+		dr.Circle(center, r*scale, 1, col.Rings)
+		if !f.NoLabels {
+			dr.Text(pixel.V(cx+3, cy+r*scale+3), formatDistance(r), col.Rings)
+		}
+	}
 
 	// Hive resources
-	honeyStore := f.stores.Honey / (1000.0 * f.energyContent.Honey)
-	decentHoney := f.stores.DecentHoney / (1000.0 * f.energyContent.Honey)
-	pollenStore := f.stores.Pollen * 0.001 * 20 * barHeight
-	idealPollen := f.stores.IdealPollen * 0.001 * 20 * barHeight
+	honeyStore := f.stores.Honey / (1000.0 * f.energyContent.Honey) * f.HoneyStoreScale
+	decentHoney := f.stores.DecentHoney / (1000.0 * f.energyContent.Honey) * f.HoneyStoreScale
+	pollenStore := f.stores.Pollen * 0.001 * f.PollenStoreScale
+	idealPollen := f.stores.IdealPollen * 0.001 * f.PollenStoreScale
 
-	drawRect(dr, pixel.V(cx-barWidth, cy+honeyStore), pixel.V(cx, cy), 0, color.RGBA{180, 180, 0, 255})
-	drawRect(dr, pixel.V(cx, cy+pollenStore), pixel.V(cx+barWidth, cy), 0, color.RGBA{180, 0, 180, 255})
+	dr.Rect(pixel.V(cx-barWidth, cy+honeyStore), pixel.V(cx, cy), 0, col.Honey)
+	dr.Rect(pixel.V(cx, cy+pollenStore), pixel.V(cx+barWidth, cy), 0, col.Pollen)
 
-	drawRect(dr, pixel.V(cx-barWidth, cy+decentHoney), pixel.V(cx, cy), 1, color.RGBA{180, 180, 120, 255})
-	drawRect(dr, pixel.V(cx, cy+idealPollen), pixel.V(cx+barWidth, cy), 1, color.RGBA{180, 120, 180, 255})
+	dr.Rect(pixel.V(cx-barWidth, cy+decentHoney), pixel.V(cx, cy), 1, col.DecentHoney)
+	dr.Rect(pixel.V(cx, cy+idealPollen), pixel.V(cx+barWidth, cy), 1, col.IdealPollen)
 
 	// Hive age classes
-	popScale := 0.2
 	popLine := 0.0
-	drawArcS(dr, pixel.V(cx, cy),
-		popScale*math.Sqrt(float64(f.popStats.TotalPopulation)),
-		popLine, color.RGBA{128, 128, 128, 255})
+	dr.Arc(center,
+		f.PopulationScale*math.Sqrt(float64(f.popStats.TotalPopulation)),
+		math.Pi, math.Pi*2, popLine, col.Population)
 
-	drawArcS(dr, pixel.V(cx, cy),
-		popScale*math.Sqrt(float64(f.popStats.TotalBrood)),
-		popLine, color.RGBA{230, 230, 230, 255})
+	dr.Arc(center,
+		f.PopulationScale*math.Sqrt(float64(f.popStats.TotalBrood)),
+		math.Pi, math.Pi*2, popLine, col.Brood)
+
+	nectarScale, pollenScale := f.patchScales()
 
 	query := f.patchFilter.Query()
 	for query.Next() {
@@ -90,68 +324,105 @@ func (f *Foraging) Draw(w *ecs.World, win *opengl.Window) {
 		px, py := cx+coords.X*scale, cy+coords.Y*scale
 
 		// Patch marker
-		drawCircle(dr, pixel.V(px, py), 3, 0, color.RGBA{128, 128, 128, 255})
+		dr.Circle(pixel.V(px, py), 3, 0, col.Patch)
 
 		// Visits
 		if vis.Nectar > 0 {
-			drawArcSW(dr, pixel.V(px, py), math.Log2(float64(vis.Nectar)), 2, color.RGBA{180, 180, 80, 255})
+			dr.Arc(pixel.V(px, py), math.Log2(float64(vis.Nectar)), math.Pi, math.Pi*1.5, 2, col.NectarVisits)
 		}
 		if vis.Pollen > 0 {
-			drawArcSE(dr, pixel.V(px, py), math.Log2(float64(vis.Pollen)), 2, color.RGBA{180, 80, 180, 255})
+			dr.Arc(pixel.V(px, py), math.Log2(float64(vis.Pollen)), math.Pi*1.5, math.Pi*2, 2, col.PollenVisits)
 		}
 
 		// Resource bars
-		nectar := res.Nectar * 0.000_001 * barHeight
-		maxNectar := res.MaxNectar * 0.000_001 * barHeight
-		pollen := res.Pollen * 0.001 * 20 * barHeight
-		maxPollen := res.MaxPollen * 0.001 * 20 * barHeight
+		nectar := res.Nectar * 0.000_001 * nectarScale
+		maxNectar := res.MaxNectar * 0.000_001 * nectarScale
+		pollen := res.Pollen * 0.001 * pollenScale
+		maxPollen := res.MaxPollen * 0.001 * pollenScale
 
 		if maxNectar > 0 {
-			drawRect(dr, pixel.V(px-barWidth, py+nectar), pixel.V(px, py), 0, color.RGBA{180, 180, 0, 255})
-			drawRect(dr, pixel.V(px-barWidth, py+maxNectar), pixel.V(px, py), 1, color.RGBA{180, 180, 80, 255})
+			dr.Rect(pixel.V(px-barWidth, py+nectar), pixel.V(px, py), 0, col.Honey)
+			dr.Rect(pixel.V(px-barWidth, py+maxNectar), pixel.V(px, py), 1, col.HoneyLimit)
 		}
 
 		if maxPollen > 0 {
-			drawRect(dr, pixel.V(px, py+pollen), pixel.V(px+barWidth, py), 0, color.RGBA{180, 0, 180, 255})
-			drawRect(dr, pixel.V(px, py+maxPollen), pixel.V(px+barWidth, py), 1, color.RGBA{180, 80, 180, 255})
+			dr.Rect(pixel.V(px, py+pollen), pixel.V(px+barWidth, py), 0, col.Pollen)
+			dr.Rect(pixel.V(px, py+maxPollen), pixel.V(px+barWidth, py), 1, col.PollenLimit)
 		}
 	}
 
-	dr.Draw(win)
-	dr.Clear()
+	if !f.NoLegend {
+		f.drawLegend(dr, nectarScale, pollenScale)
+	}
 }
 
-func drawCircle(dr *imdraw.IMDraw, center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
-	dr.Color = color
-	dr.Push(center)
-	dr.Circle(radius, thickness)
-	dr.Reset()
-}
+// patchScales returns the scales for patch nectar and pollen bars.
+// Scales that are not set are calculated from the maximum resources over all patches.
+func (f *Foraging) patchScales() (float64, float64) {
+	nectarScale, pollenScale := f.NectarScale, f.PollenScale
+	if nectarScale > 0 && pollenScale > 0 {
+		return nectarScale, pollenScale
+	}
 
-func drawArcSW(dr *imdraw.IMDraw, center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
-	dr.Color = color
-	dr.Push(center)
-	dr.CircleArc(radius, math.Pi, math.Pi*1.5, thickness)
-	dr.Reset()
+	maxNectar, maxPollen := 0.0, 0.0
+	query := f.patchFilter.Query()
+	for query.Next() {
+		_, res, _ := query.Get()
+		maxNectar = math.Max(maxNectar, res.MaxNectar*0.000_001)
+		maxPollen = math.Max(maxPollen, res.MaxPollen*0.001)
+	}
+	if nectarScale <= 0 {
+		nectarScale = 0
+		if maxNectar > 0 {
+			nectarScale = f.MaxBarHeight / maxNectar
+		}
+	}
+	if pollenScale <= 0 {
+		pollenScale = 0
+		if maxPollen > 0 {
+			pollenScale = f.MaxBarHeight / maxPollen
+		}
+	}
+	return nectarScale, pollenScale
 }
 
-func drawArcSE(dr *imdraw.IMDraw, center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
-	dr.Color = color
-	dr.Push(center)
-	dr.CircleArc(radius, math.Pi*1.5, math.Pi*2, thickness)
-	dr.Reset()
+// drawLegend draws the legend in the bottom left corner.
+func (f *Foraging) drawLegend(dr canvas, nectarScale, pollenScale float64) {
+	col := &f.Colors
+	entries := []struct {
+		Color color.RGBA
+		Label string
+	}{
+		{col.Honey, "Honey store, patch nectar" + barLabel(f.MaxBarHeight, nectarScale, "L")},
+		{col.Pollen, "Pollen store, patch pollen" + barLabel(f.MaxBarHeight, pollenScale, "kg")},
+		{col.Population, "Population"},
+		{col.Brood, "Brood"},
+		{col.NectarVisits, "Nectar visits (log2)"},
+		{col.PollenVisits, "Pollen visits (log2)"},
+	}
+
+	lineHeight := 15.0
+	x, y := 8.0, 8.0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		dr.Rect(pixel.V(x, y), pixel.V(x+10, y+10), 0, e.Color)
+		dr.Text(pixel.V(x+16, y+1), e.Label, col.Text)
+		y += lineHeight
+	}
 }
 
-func drawArcS(dr *imdraw.IMDraw, center pixel.Vec, radius float64, thickness float64, color color.RGBA) {
-	dr.Color = color
-	dr.Push(center)
-	dr.CircleArc(radius, math.Pi, math.Pi*2, thickness)
-	dr.Reset()
+// barLabel formats the amount represented by a bar of the given height.
+func barLabel(height, scale float64, unit string) string {
+	if scale <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%.3g %s per %.0f px)", height/scale, unit, height)
 }
 
-func drawRect(dr *imdraw.IMDraw, p1, p2 pixel.Vec, thickness float64, color color.RGBA) {
-	dr.Color = color
-	dr.Push(p1, p2)
-	dr.Rectangle(thickness)
-	dr.Reset()
+// formatDistance formats a distance in meters for ring labels.
+func formatDistance(d float64) string {
+	if d >= 1000 {
+		return fmt.Sprintf("%g km", d/1000)
+	}
+	return fmt.Sprintf("%g m", d)
 }